@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/chrisfenner/tpm-top/pkg/auth"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+func changeAuth(tpm io.ReadWriter, args []string) int {
+	fs := flag.NewFlagSet("change-auth", flag.ContinueOnError)
+	currentAuth := auth.AddFlags(fs, "current")
+	newAuth := auth.AddValueFlags(fs, "new-", "new")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return 1
+	}
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "'change-auth' command expects 1 argument: owner, endorsement, lockout or platform\n")
+		return 1
+	}
+	hierarchy, err := auth.Hierarchy(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	value, err := newAuth.Value()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	session, err := currentAuth.Session(tpm, auth.Handle{Handle: hierarchy})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not start the %s authorization session: %v\n", args[0], err)
+		return 1
+	}
+	defer session.Close(tpm)
+	handles := []auth.Handle{{Handle: hierarchy, Session: session, NewAuth: value}}
+	if _, err := auth.Run(tpm, tpm2.CmdHierarchyChangeAuth, handles, tpmutil.U16Bytes(value)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error in TPM2_HierarchyChangeAuth: %v\n", err)
+		return 1
+	}
+	return 0
+}
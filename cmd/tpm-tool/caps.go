@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/chrisfenner/tpm-top/pkg/caps"
+	"github.com/chrisfenner/tpm-top/pkg/cc"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// algorithmAttributes are the names of the TPMA_ALGORITHM bits.
+var algorithmAttributes = []struct {
+	bit  tpm2.AlgorithmAttributes
+	name string
+}{
+	{1 << 0, "asymmetric"},
+	{1 << 1, "symmetric"},
+	{1 << 2, "hash"},
+	{1 << 3, "object"},
+	{1 << 8, "signing"},
+	{1 << 9, "encrypting"},
+	{1 << 10, "method"},
+}
+
+func capabilities(tpm io.ReadWriter, args []string) int {
+	if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "'caps' command expects at most 1 argument: algs or commands\n")
+		return 1
+	}
+	what := "all"
+	if len(args) == 1 {
+		what = args[0]
+	}
+	switch what {
+	case "all":
+		if ret := capsAlgorithms(tpm); ret != 0 {
+			return ret
+		}
+		fmt.Printf("\n")
+		return capsCommands(tpm)
+	case "algs":
+		return capsAlgorithms(tpm)
+	case "commands":
+		return capsCommands(tpm)
+	}
+	fmt.Fprintf(os.Stderr, "Unrecognized capability '%s'. Expected algs or commands.\n", what)
+	return 1
+}
+
+// capsAlgorithms prints the algorithms the TPM implements.
+func capsAlgorithms(tpm io.ReadWriter) int {
+	algs, err := caps.Algorithms(tpm)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading TPM_CAP_ALGS: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Algorithms (%d):\n", len(algs))
+	for _, a := range algs {
+		attrs := make([]string, 0)
+		for _, attr := range algorithmAttributes {
+			if a.Attributes&attr.bit != 0 {
+				attrs = append(attrs, attr.name)
+			}
+		}
+		fmt.Printf("  0x%04x %s: %s\n", uint16(a.ID), alg.Name(a.ID), strings.Join(attrs, ", "))
+	}
+	return 0
+}
+
+// capsCommands prints the commands the TPM implements.
+func capsCommands(tpm io.ReadWriter) int {
+	attrs, err := caps.Commands(tpm)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading TPM_CAP_COMMANDS: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Commands (%d):\n", len(attrs))
+	for _, a := range attrs {
+		fmt.Printf("  0x%03x %s: %v\n", uint32(a.Code()), cc.Name(a.Code()), a)
+	}
+	return 0
+}
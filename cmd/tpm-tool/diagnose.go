@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chrisfenner/tpm-top/pkg/caps"
+	"github.com/chrisfenner/tpm-top/pkg/rc"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// inLockout is the TPMA_PERMANENT bit that is set when the TPM is in DA lockout.
+const inLockout = 1 << 9
+
+// diagnose queries the TPM for the state relevant to the given response code,
+// and returns concrete guidance about it.
+func diagnose(tpm io.ReadWriter, code rc.Code) ([]string, error) {
+	switch code.Name {
+	case "TPM_RC_INITIALIZE", "TPM_RC_REBOOT":
+		return diagnoseStartup(tpm)
+	case "TPM_RC_LOCKOUT", "TPM_RC_AUTH_FAIL":
+		return diagnoseLockout(tpm)
+	case "TPM_RC_OBJECT_MEMORY", "TPM_RC_OBJECT_HANDLES":
+		return diagnoseObjects(tpm)
+	case "TPM_RC_SESSION_MEMORY", "TPM_RC_SESSION_HANDLES", "TPM_RC_CONTEXT_GAP":
+		return diagnoseSessions(tpm)
+	case "TPM_RC_MEMORY":
+		objects, err := diagnoseObjects(tpm)
+		if err != nil {
+			return nil, err
+		}
+		sessions, err := diagnoseSessions(tpm)
+		if err != nil {
+			return nil, err
+		}
+		return append(objects, sessions...), nil
+	}
+	return []string{fmt.Sprintf("No diagnosis is available for %s.", code.Name)}, nil
+}
+
+// diagnoseStartup checks whether the TPM has been started up.
+func diagnoseStartup(tpm io.ReadWriter) ([]string, error) {
+	_, _, err := tpm2.GetCapability(tpm, tpm2.CapabilityTPMProperties, 1, uint32(tpm2.TPMAStartupClear))
+	var fmt0 tpm2.Error
+	if errors.As(err, &fmt0) && fmt0.Code == tpm2.RCInitialize {
+		return []string{"The TPM has not been started up. Run `tpm-tool startup`."}, nil
+	}
+	if err != nil {
+		return nil, rc.WithCommand(err, tpm2.CmdGetCapability)
+	}
+	return []string{"The TPM is started up. TPM2_Startup may have been sent more than once."}, nil
+}
+
+// diagnoseLockout reports the state of the dictionary attack protection.
+func diagnoseLockout(tpm io.ReadWriter) ([]string, error) {
+	permanent, err := caps.Property(tpm, tpm2.TPMAPermanent)
+	if err != nil {
+		return nil, err
+	}
+	counter, err := caps.Property(tpm, tpm2.LockoutCounter)
+	if err != nil {
+		return nil, err
+	}
+	maxTries, err := caps.Property(tpm, tpm2.MaxAuthFail)
+	if err != nil {
+		return nil, err
+	}
+	interval, err := caps.Property(tpm, tpm2.LockoutInterval)
+	if err != nil {
+		return nil, err
+	}
+	recovery, err := caps.Property(tpm, tpm2.LockoutRecovery)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []string{fmt.Sprintf("The failure counter is %d out of %d allowed failures.", counter, maxTries)}
+	if interval == 0 {
+		result = append(result, "The lockout interval is 0, so dictionary attack protection is disabled.")
+		return result, nil
+	}
+	if permanent&inLockout != 0 {
+		result = append(result,
+			fmt.Sprintf("The TPM is in lockout. It will leave lockout when the counter decrements, at most %d seconds from now.", interval),
+			"To leave lockout now, run TPM2_DictionaryAttackLockReset with lockout authorization.",
+			fmt.Sprintf("If lockout authorization fails, it cannot be retried for %d seconds.", recovery))
+	} else {
+		result = append(result,
+			fmt.Sprintf("%d more failures will put the TPM in lockout. One failure is forgiven every %d seconds.", maxTries-counter, interval))
+	}
+	return result, nil
+}
+
+// diagnoseObjects reports the transient objects that are loaded.
+func diagnoseObjects(tpm io.ReadWriter) ([]string, error) {
+	handles, err := caps.Handles(tpm, tpm2.TransientFirst)
+	if err != nil {
+		return nil, err
+	}
+	avail, err := caps.Property(tpm, tpm2.HRTransientAvail)
+	if err != nil {
+		return nil, err
+	}
+	result := []string{fmt.Sprintf("%d transient objects are loaded%s, with room for about %d more.", len(handles), handleList(handles), avail)}
+	if len(handles) != 0 {
+		result = append(result, "Flush the ones that are no longer needed with TPM2_FlushContext.")
+	}
+	return result, nil
+}
+
+// diagnoseSessions reports the sessions that are loaded or saved.
+func diagnoseSessions(tpm io.ReadWriter) ([]string, error) {
+	loaded, err := caps.Sessions(tpm, false)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := caps.Sessions(tpm, true)
+	if err != nil {
+		return nil, err
+	}
+	loadedAvail, err := caps.Property(tpm, tpm2.HRLoadedAvail)
+	if err != nil {
+		return nil, err
+	}
+	activeAvail, err := caps.Property(tpm, tpm2.HRActiveAvail)
+	if err != nil {
+		return nil, err
+	}
+	result := []string{
+		fmt.Sprintf("%d sessions are loaded%s, with room for about %d more.", len(loaded), handleList(loaded), loadedAvail),
+		fmt.Sprintf("%d sessions are saved%s, with room for %d more active sessions.", len(saved), handleList(saved), activeAvail),
+	}
+	if len(loaded)+len(saved) != 0 {
+		result = append(result, "Flush the ones that are no longer needed with TPM2_FlushContext.")
+	}
+	return result, nil
+}
+
+// handleList formats a list of handles for a diagnosis.
+func handleList(handles []tpmutil.Handle) string {
+	if len(handles) == 0 {
+		return ""
+	}
+	strs := make([]string, 0, len(handles))
+	for _, h := range handles {
+		strs = append(strs, fmt.Sprintf("0x%08x", h))
+	}
+	return " (" + strings.Join(strs, ", ") + ")"
+}
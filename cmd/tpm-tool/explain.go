@@ -0,0 +1,269 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/chrisfenner/tpm-top/pkg/cc"
+	"github.com/chrisfenner/tpm-top/pkg/rc"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+func explain(args []string) int {
+	fs := flag.NewFlagSet("explain", flag.ContinueOnError)
+	list := fs.Bool("list", false, "list all the known error codes, grouped by format")
+	handle := fs.Int("handle", 0, "build the FMT1 error code for the given handle number")
+	parameter := fs.Int("parameter", 0, "build the FMT1 error code for the given parameter number")
+	session := fs.Int("session", 0, "build the FMT1 error code for the given session number")
+	command := fs.String("command", "", "name or code of the command that returned the error")
+	diag := fs.Bool("diagnose", false, "query the TPM for the state relevant to the error")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return 1
+	}
+	if *list {
+		if len(args) != 0 {
+			fmt.Fprintf(os.Stderr, "'explain --list' expects 0 arguments\n")
+			return 1
+		}
+		return explainList()
+	}
+	if len(args) == 0 && *command != "" && !*diag {
+		c, ok := lookupCommand(*command)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unrecognized command '%s'\n", *command)
+			return 1
+		}
+		return explainCommand(c)
+	}
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "'explain' command expects 1 argument: an error code, command name or keyword\n")
+		return 1
+	}
+
+	// Collect the FMT1 relation, if one was requested.
+	relations := 0
+	var rel rc.Relation
+	var idx int
+	if *handle != 0 {
+		relations++
+		rel, idx = rc.Handle, *handle
+	}
+	if *parameter != 0 {
+		relations++
+		rel, idx = rc.Parameter, *parameter
+	}
+	if *session != 0 {
+		relations++
+		rel, idx = rc.Session, *session
+	}
+	if relations > 1 {
+		fmt.Fprintf(os.Stderr, "only one of --handle, --parameter and --session may be given\n")
+		return 1
+	}
+
+	var cmd *cc.Command
+	if *command != "" {
+		c, ok := lookupCommand(*command)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unrecognized command '%s'\n", *command)
+			return 1
+		}
+		cmd = &c
+	}
+
+	var value int
+	// exact is the error that a name matched, if any.
+	var exact *rc.Code
+	if code, err := strconv.ParseInt(args[0], 0, 32); err == nil {
+		if relations != 0 {
+			fmt.Fprintf(os.Stderr, "--handle, --parameter and --session require an error name, not a number\n")
+			return 1
+		}
+		value = int(code)
+	} else {
+		code, ok := rc.Lookup(args[0])
+		if !ok {
+			if relations != 0 || *diag {
+				fmt.Fprintf(os.Stderr, "unrecognized error name '%s'\n", args[0])
+				return 1
+			}
+			if c, ok := cc.Lookup(args[0]); ok && cmd == nil {
+				return explainCommand(c)
+			}
+			return explainSearch(args[0])
+		}
+		exact = &code
+		value = code.Value()
+		if relations != 0 {
+			value, err = rc.MakeFmt1(code, rel, idx)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "could not build error code: %v\n", err)
+				return 1
+			}
+		}
+	}
+	if ret := explainValue(value, cmd); ret != 0 {
+		return ret
+	}
+	if *diag {
+		return explainDiagnosis(value)
+	}
+	// A name like "policy" is also a keyword for other errors and commands.
+	if exact != nil && relations == 0 && cmd == nil {
+		codes, cmds := search(args[0])
+		others := make([]rc.Code, 0, len(codes))
+		for _, code := range codes {
+			if code != *exact {
+				others = append(others, code)
+			}
+		}
+		if len(others) != 0 || len(cmds) != 0 {
+			fmt.Printf("\nOther matches for '%s':\n", args[0])
+			printMatches(others, cmds)
+		}
+	}
+	return 0
+}
+
+// lookupCommand finds a command by name or by command code.
+func lookupCommand(nameOrCode string) (cc.Command, bool) {
+	if code, err := strconv.ParseUint(nameOrCode, 0, 32); err == nil {
+		return cc.ByCode(tpmutil.Command(code))
+	}
+	return cc.Lookup(nameOrCode)
+}
+
+// explainCommand prints the handles, sessions, parameters and attributes of a
+// command.
+func explainCommand(cmd cc.Command) int {
+	fmt.Printf("(0x%x) %s\n", uint32(cmd.Code), cmd.Name)
+	if len(cmd.Handles) == 0 {
+		fmt.Printf("Handles: none\n")
+	} else {
+		fmt.Printf("Handles:\n")
+		for i, h := range cmd.Handles {
+			if strings.HasPrefix(h, "@") {
+				fmt.Printf("  %d: %s (requires authorization)\n", i+1, strings.TrimPrefix(h, "@"))
+			} else {
+				fmt.Printf("  %d: %s\n", i+1, h)
+			}
+		}
+	}
+	if auths := cmd.AuthHandles(); len(auths) == 0 {
+		fmt.Printf("Sessions: none required\n")
+	} else {
+		fmt.Printf("Sessions: %d required\n", len(auths))
+		for i, h := range auths {
+			fmt.Printf("  %d: authorization for %s\n", i+1, h)
+		}
+	}
+	if len(cmd.Parameters) == 0 {
+		fmt.Printf("Parameters: none\n")
+	} else {
+		fmt.Printf("Parameters:\n")
+		for i, p := range cmd.Parameters {
+			fmt.Printf("  %d: %s\n", i+1, p)
+		}
+	}
+	if cmd.ResponseHandle != "" {
+		fmt.Printf("Response handle: %s\n", cmd.ResponseHandle)
+	}
+	fmt.Printf("Attributes: 0x%08x (%v)\n", uint32(cmd.Attributes), cmd.Attributes)
+	return 0
+}
+
+// explainValue prints the explanation of a single error code, returned by cmd
+// if it is not nil.
+func explainValue(code int, cmd *cc.Command) int {
+	var err error
+	if cmd != nil {
+		err = rc.MakeCommandError(code, cmd.Code)
+	} else {
+		err = rc.MakeError(code)
+	}
+	if err == nil {
+		fmt.Printf("RC_SUCCESS! 😎\n")
+	} else {
+		fmt.Printf("%s\n", err)
+	}
+	if remedy := rc.Remedy(code); remedy != "" {
+		fmt.Printf("Hint: %s\n", remedy)
+	}
+	return 0
+}
+
+// explainDiagnosis connects to the TPM and prints a diagnosis of the error code.
+func explainDiagnosis(value int) int {
+	code, ok := rc.Decode(value)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "cannot diagnose unrecognized error code 0x%x\n", value)
+		return 1
+	}
+	tpm, err := openTpm()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening TPM simulator: %v\n", err)
+		return 1
+	}
+	defer tpm.Close()
+	diagnosis, err := diagnose(tpm, code)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not diagnose %s: %v\n", code.Name, err)
+		return 1
+	}
+	fmt.Printf("Diagnosis:\n")
+	for _, line := range diagnosis {
+		fmt.Printf("  %s\n", line)
+	}
+	return 0
+}
+
+// explainSearch prints all the error codes and commands matching the keyword.
+func explainSearch(keyword string) int {
+	codes, cmds := search(keyword)
+	if len(codes) == 0 && len(cmds) == 0 {
+		fmt.Fprintf(os.Stderr, "no error codes or commands match '%s'\n", keyword)
+		return 1
+	}
+	printMatches(codes, cmds)
+	return 0
+}
+
+// search finds the error codes whose name or description contains the
+// keyword, and the commands whose name contains it.
+func search(keyword string) ([]rc.Code, []cc.Command) {
+	cmds := make([]cc.Command, 0)
+	for _, cmd := range cc.Commands() {
+		if strings.Contains(strings.ToLower(cmd.Name), strings.ToLower(keyword)) {
+			cmds = append(cmds, cmd)
+		}
+	}
+	return rc.Search(keyword), cmds
+}
+
+// printMatches prints the error codes and commands that a search found.
+func printMatches(codes []rc.Code, cmds []cc.Command) {
+	for _, code := range codes {
+		fmt.Printf("%s\n", rc.MakeError(code.Value()))
+	}
+	for _, cmd := range cmds {
+		fmt.Printf("(0x%x) %s\n", uint32(cmd.Code), cmd.Name)
+	}
+}
+
+// explainList prints all the known error codes, grouped by format.
+func explainList() int {
+	for i, f := range []rc.Format{rc.Ver1, rc.Fmt1, rc.Warn} {
+		if i != 0 {
+			fmt.Printf("\n")
+		}
+		fmt.Printf("%v:\n", f)
+		for _, code := range rc.CodesOfFormat(f) {
+			fmt.Printf("  0x%03x %s: %s\n", code.Value(), code.Name, code.Description)
+		}
+	}
+	return 0
+}
@@ -1,16 +1,20 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"strconv"
-	"strings"
 
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/chrisfenner/tpm-top/pkg/auth"
 	"github.com/chrisfenner/tpm-top/pkg/opener"
 	pcrAllocate "github.com/chrisfenner/tpm-top/pkg/pcr-allocate"
+	"github.com/chrisfenner/tpm-top/pkg/pcrs"
 	"github.com/chrisfenner/tpm-top/pkg/rc"
+	"github.com/chrisfenner/tpm-top/pkg/trace"
 	"github.com/google/go-attestation/attest"
 	"github.com/google/go-tpm/tpm2"
 	"github.com/google/go-tpm/tpmutil"
@@ -19,17 +23,24 @@ import (
 type toolFunc func(io.ReadWriter, []string) int
 
 var funcMap = map[string]toolFunc{
-	"startup":   startup,
-	"shutdown":  shutdown,
-	"pcr-banks": pcrBanks,
-	"extend":    extend,
+	"startup":     startup,
+	"shutdown":    shutdown,
+	"pcr-banks":   pcrBanks,
+	"extend":      extend,
+	"caps":        capabilities,
+	"pcr-save":    pcrSave,
+	"pcr-check":   pcrCheck,
+	"change-auth": changeAuth,
 }
 
 type toolFuncNoTpm func([]string) int
 
 var funcMapNoTpm = map[string]toolFuncNoTpm{
-	"explain": explain,
-	"dump":    dump,
+	"explain":     explain,
+	"dump":        dump,
+	"pcr-diff":    pcrDiff,
+	"pcr-predict": pcrPredict,
+	"policy-pcr":  policyPcr,
 }
 
 func startup(tpm io.ReadWriter, args []string) int {
@@ -39,6 +50,7 @@ func startup(tpm io.ReadWriter, args []string) int {
 	}
 	err := tpm2.Startup(tpm, tpm2.StartupClear)
 	if err != nil {
+		err = rc.WithCommand(err, tpm2.CmdStartup)
 		fmt.Fprintf(os.Stderr, "Error calling TPM2_Startup: %v\n", err)
 		return 1
 	}
@@ -52,6 +64,7 @@ func shutdown(tpm io.ReadWriter, args []string) int {
 	}
 	err := tpm2.Shutdown(tpm, tpm2.StartupClear)
 	if err != nil {
+		err = rc.WithCommand(err, tpm2.CmdShutdown)
 		fmt.Fprintf(os.Stderr, "Error calling TPM2_Shutdown: %v\n", err)
 		return 1
 	}
@@ -59,49 +72,91 @@ func shutdown(tpm io.ReadWriter, args []string) int {
 }
 
 func pcrBanks(tpm io.ReadWriter, args []string) int {
+	fs := flag.NewFlagSet("pcr-banks", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "show the changes to the PCR allocation without making them")
+	platformAuth := auth.AddFlags(fs, "platform")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return 1
+	}
 	if len(args) == 0 {
-		fmt.Fprintf(os.Stderr, "'pcr-banks' command expects at least one hash algorithm\n")
-		return 1
-	}
-	algs := make([]tpm2.Algorithm, 0)
-	for _, algStr := range args {
-		algStr = strings.ToLower(algStr)
-		var alg tpm2.Algorithm
-		switch algStr {
-		case "sha1":
-			alg = tpm2.AlgSHA1
-		case "sha256":
-			fallthrough
-		case "sha2-256":
-			alg = tpm2.AlgSHA256
-		case "sha384":
-			fallthrough
-		case "sha2-384":
-			alg = tpm2.AlgSHA384
-		case "sha512":
-			fallthrough
-		case "sha2-512":
-			alg = tpm2.AlgSHA512
-		case "sha3-256":
-			alg = tpm2.AlgSHA3_256
-		case "sha3-384":
-			alg = tpm2.AlgSHA3_384
-		case "sha3-512":
-			alg = tpm2.AlgSHA3_512
-		default:
-			fmt.Fprintf(os.Stderr, "Unrecognized hash algorithm '%s'\n", algStr)
-			return 1
-		}
-		algs = append(algs, alg)
+		fmt.Fprintf(os.Stderr, "'pcr-banks' command expects at least one hash algorithm or allocation like sha256:0-23\n")
+		return 1
+	}
+	layout, err := pcrs.GetLayout(tpm)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read the number of PCRs: %v\n", err)
+		return 1
+	}
+	banks, err := pcrAllocate.ParseAllocation(layout, args...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	plan, err := pcrAllocate.NewPlan(tpm, banks)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	printPlan(plan)
+	if *dryRun {
+		fmt.Printf("No TPM capability reports how much PCR memory the TPM has: only sizeAvailable in the TPM2_PCR_Allocate response tells whether the banks fit.\n")
+		fmt.Printf("Dry run: the allocation was not changed.\n")
+		return 0
+	}
+
+	session, err := platformAuth.Session(tpm, auth.Handle{Handle: tpm2.HandlePlatform})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not start the platform authorization session: %v\n", err)
+		return 1
 	}
-	if err := pcrAllocate.PcrAllocate(tpm, algs); err != nil {
+	defer session.Close(tpm)
+	result, err := plan.Apply(tpm, session)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error calling TPM2_PCR_ALLOCATE: %v\n", err)
 		return 1
 	}
+	if result.AllocationSuccess {
+		fmt.Printf("The TPM accepted the allocation. It takes effect after the next TPM reset.\n")
+	} else {
+		fmt.Printf("The TPM refused the allocation.\n")
+	}
+	fmt.Printf("  PCRs per bank:        %d\n", result.MaxPCR)
+	fmt.Printf("  PCR memory needed:    %d bytes\n", result.SizeNeeded)
+	fmt.Printf("  PCR memory available: %d bytes\n", result.SizeAvailable)
+	if !result.AllocationSuccess {
+		if result.SizeNeeded > result.SizeAvailable {
+			fmt.Printf("The banks need more PCR memory than the TPM has.\n")
+		}
+		return 1
+	}
 	return 0
 }
 
+// printPlan prints the current and proposed PCR allocation of each bank that
+// is allocated now or will be.
+func printPlan(plan *pcrAllocate.Plan) {
+	fmt.Printf("PCR allocation (current -> proposed):\n")
+	shown := make(map[tpm2.Algorithm]bool)
+	for _, change := range plan.Changes {
+		shown[change.Hash] = true
+		fmt.Printf("  %-10s %s -> %s\n", alg.Name(change.Hash)+":", pcrs.FormatIndices(change.Current), pcrs.FormatIndices(change.Proposed))
+	}
+	for _, bank := range plan.Banks {
+		if !shown[bank.Hash] && len(bank.PCRs) != 0 {
+			fmt.Printf("  %-10s %s (unchanged)\n", alg.Name(bank.Hash)+":", pcrs.FormatIndices(bank.PCRs))
+		}
+	}
+	fmt.Printf("PCR memory needed: %d bytes\n", plan.SizeNeeded())
+}
+
 func extend(tpm io.ReadWriter, args []string) int {
+	fs := flag.NewFlagSet("extend", flag.ContinueOnError)
+	pcrAuth := auth.AddFlags(fs, "PCR")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return 1
+	}
 	if len(args) != 2 {
 		fmt.Fprintf(os.Stderr, "'extend' command expects 2 arguments: a file and an index")
 		return 1
@@ -111,8 +166,13 @@ func extend(tpm io.ReadWriter, args []string) int {
 		fmt.Fprintf(os.Stderr, "Could not parse PCR index: %v\n", err)
 		return 1
 	}
-	if pcrIndex < 0 || pcrIndex > 23 {
-		fmt.Fprintf(os.Stderr, "PCR index must be between 0 and 23.\n")
+	layout, err := pcrs.GetLayout(tpm)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read the number of PCRs: %v\n", err)
+		return 1
+	}
+	if pcrIndex < 0 || pcrIndex >= layout.Count {
+		fmt.Fprintf(os.Stderr, "PCR index must be between 0 and %d.\n", layout.Count-1)
 		return 1
 	}
 	eventFile := args[1]
@@ -136,31 +196,64 @@ func extend(tpm io.ReadWriter, args []string) int {
 		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", eventFile, err)
 		return 1
 	}
-	if err := tpm2.PCREvent(tpm, tpmutil.Handle(pcrIndex), contents); err != nil {
+	session, err := pcrAuth.Session(tpm, auth.Handle{Handle: tpmutil.Handle(pcrIndex)})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not start the PCR authorization session: %v\n", err)
+		return 1
+	}
+	defer session.Close(tpm)
+	handles := []auth.Handle{{Handle: tpmutil.Handle(pcrIndex), Session: session}}
+	resp, err := auth.Run(tpm, tpm2.CmdPCREvent, handles, tpmutil.U16Bytes(contents))
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error in TPM2_PCR_EVENT: %v\n", err)
 		return 1
 	}
+	digests, err := decodeDigestValues(resp)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not decode TPM2_PCR_Event response: %v\n", err)
+		return 1
+	}
+	for _, d := range digests {
+		fmt.Printf("%s: %x\n", alg.Name(d.hash), d.digest)
+	}
 
 	return 0
 }
 
-func explain(args []string) int {
-	if len(args) != 1 {
-		fmt.Fprintf(os.Stderr, "'explain' command expects 1 argument: an error code\n")
-		return 1
-	}
-	code, err := strconv.ParseInt(args[0], 0, 32)
+// taggedDigest is a TPMT_HA.
+type taggedDigest struct {
+	hash   tpm2.Algorithm
+	digest []byte
+}
+
+// decodeDigestValues decodes a TPML_DIGEST_VALUES, like the digests that
+// TPM2_PCR_Event extended into each bank.
+func decodeDigestValues(b []byte) ([]taggedDigest, error) {
+	var count uint32
+	read, err := tpmutil.Unpack(b, &count)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "could not parse error code: %v\n", err)
-		return 1
+		return nil, err
 	}
-	err = rc.MakeError(int(code))
-	if err == nil {
-		fmt.Printf("RC_SUCCESS! 😎\n")
-	} else {
-		fmt.Printf("%s\n", err)
+	b = b[read:]
+	result := make([]taggedDigest, 0, count)
+	for i := 0; i < int(count); i++ {
+		var hash tpm2.Algorithm
+		read, err := tpmutil.Unpack(b, &hash)
+		if err != nil {
+			return nil, err
+		}
+		b = b[read:]
+		a, ok := alg.ByID(hash)
+		if !ok || !a.IsHash() {
+			return nil, fmt.Errorf("digest %d has unknown hash algorithm %s", i, alg.Name(hash))
+		}
+		if len(b) < a.DigestSize {
+			return nil, fmt.Errorf("%v digest is %d bytes, expected %d bytes", a, len(b), a.DigestSize)
+		}
+		result = append(result, taggedDigest{hash: hash, digest: b[:a.DigestSize]})
+		b = b[a.DigestSize:]
 	}
-	return 0
+	return result, nil
 }
 
 func dump(args []string) int {
@@ -190,8 +283,28 @@ func dump(args []string) int {
 
 }
 
+// parseArgs parses the flags in args, which may be interleaved with positional
+// arguments, and returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := make([]string, 0)
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// traceTpm is set by the --trace flag, to log every command and response.
+var traceTpm = flag.Bool("trace", false, "log every TPM command and response to stderr")
+
 func usage() {
-	fmt.Printf("tpm-tool usage: tpm-tool (function) [(arguments)]\n")
+	fmt.Printf("tpm-tool usage: tpm-tool [--trace] (function) [(arguments)]\n")
 	fmt.Printf("Supported functions:\n")
 	for name, _ := range funcMap {
 		fmt.Printf("  %s\n", name)
@@ -201,16 +314,34 @@ func usage() {
 	}
 }
 
+// openTpm opens a connection to the TPM simulator.
+func openTpm() (io.ReadWriteCloser, error) {
+	conf := opener.TcpConfig{
+		Address: "127.0.0.1:2321",
+	}
+	tpm, err := opener.OpenTcpTpm(&conf)
+	if err != nil {
+		return nil, err
+	}
+	if *traceTpm {
+		tpm = trace.New(tpm, os.Stderr)
+	}
+	return tpm, nil
+}
+
 func mainWithExitCode() int {
-	if len(os.Args) < 2 {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
 		fmt.Fprintf(os.Stderr, "Please specify a command.\n")
 		usage()
 		return 1
 	}
-	cmd := os.Args[1]
+	cmd := flag.Arg(0)
+	args := flag.Args()[1:]
 	funNoTpm, ok := funcMapNoTpm[cmd]
 	if ok {
-		return funNoTpm(os.Args[2:])
+		return funNoTpm(args)
 	}
 	fun, ok := funcMap[cmd]
 	if !ok {
@@ -219,17 +350,14 @@ func mainWithExitCode() int {
 		return 1
 	}
 
-	conf := opener.TcpConfig{
-		Address: "127.0.0.1:2321",
-	}
-	tpm, err := opener.OpenTcpTpm(&conf)
+	tpm, err := openTpm()
 	if err != nil {
 		fmt.Printf("Error opening TPM simulator: %v\n", err)
 		return 1
 	}
 	defer tpm.Close()
 
-	return fun(tpm, os.Args[2:])
+	return fun(tpm, args)
 }
 
 func main() {
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/chrisfenner/tpm-top/pkg/pcrs"
+)
+
+func policyPcr(args []string) int {
+	fs := flag.NewFlagSet("policy-pcr", flag.ContinueOnError)
+	hashName := fs.String("hash", "sha256", "the hash algorithm of the policy")
+	from := fs.String("from", "live", "take the PCR values from a saved snapshot, or 'live' for the TPM's current values")
+	var extends stringList
+	fs.Var(&extends, "extend", "extend an event into a PCR before computing the policy, as <index>=<event> (may be repeated)")
+	start := fs.String("policy", "", "the policy digest before TPM2_PolicyPCR, in hex (default all zeros)")
+	verify := fs.Bool("verify", false, "check the policy digest against one computed by the TPM in a trial session")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return 1
+	}
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "'policy-pcr' command expects 1 argument: a PCR selection like sha256:0,1,7\n")
+		return 1
+	}
+	sels, err := pcrs.ParseSelection(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	hash, err := alg.LookupHash(*hashName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	if *verify && *start != "" {
+		fmt.Fprintf(os.Stderr, "--verify only supports policies that start with TPM2_PolicyPCR\n")
+		return 1
+	}
+	policyDigest := make([]byte, hash.DigestSize)
+	if *start != "" {
+		policyDigest, err = hex.DecodeString(strings.TrimPrefix(*start, "0x"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid policy digest: %v\n", err)
+			return 1
+		}
+	}
+
+	var tpm io.ReadWriteCloser
+	layout := pcrs.PCClientLayout
+	if *from == "live" || *verify {
+		tpm, err = openTpm()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening TPM simulator: %v\n", err)
+			return 1
+		}
+		defer tpm.Close()
+		layout, err = pcrs.GetLayout(tpm)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not read the number of PCRs: %v\n", err)
+			return 1
+		}
+	}
+	var snap *pcrs.Snapshot
+	if *from == "live" {
+		snap, err = pcrs.NewReader(tpm).Snapshot()
+	} else {
+		snap, err = loadSnapshot(*from)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not load PCRs from %s: %v\n", *from, err)
+		return 1
+	}
+	for _, e := range extends {
+		parts := strings.SplitN(e, "=", 2)
+		pcr, err := strconv.Atoi(parts[0])
+		if len(parts) != 2 || err != nil || pcr < 0 {
+			fmt.Fprintf(os.Stderr, "Invalid --extend %q: expected <index>=<event>\n", e)
+			return 1
+		}
+		event, err := parseEvent(parts[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid event %q: %v\n", parts[1], err)
+			return 1
+		}
+		snap, err = snap.Predict(pcr, event)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not predict the PCR values: %v\n", err)
+			return 1
+		}
+	}
+
+	pcrDigest, err := pcrs.PCRDigest(hash.ID, snap, sels)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not compute the PCR digest: %v\n", err)
+		return 1
+	}
+	policy, err := pcrs.PolicyPCR(hash.ID, policyDigest, layout, snap, sels)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not compute the policy digest: %v\n", err)
+		return 1
+	}
+	fmt.Printf("pcrDigest:    %x\n", pcrDigest)
+	fmt.Printf("policyDigest: %x\n", policy)
+	if !*verify {
+		return 0
+	}
+
+	// With the live values, let the TPM read the PCRs itself, to check the
+	// PCR digest too.
+	expected := pcrDigest
+	if *from == "live" && len(extends) == 0 {
+		expected = nil
+	}
+	trial, err := pcrs.TrialPolicyPCR(tpm, hash.ID, layout, sels, expected)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not compute the policy digest in a trial session: %v\n", err)
+		return 1
+	}
+	if !bytes.Equal(trial, policy) {
+		fmt.Printf("The TPM computed a different policy digest: %x\n", trial)
+		return 1
+	}
+	fmt.Printf("The TPM computed the same policy digest in a trial session.\n")
+	return 0
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/chrisfenner/tpm-top/pkg/pcrs"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// maxEventSize is the most data TPM2_PCR_Event accepts.
+const maxEventSize = 1024
+
+// stringList is a flag that may be given more than once.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseDigest parses a hash algorithm and a hex digest, given as
+// "<alg>:<hex>". The digest may be left out if allowEmpty is set.
+func parseDigest(s string, allowEmpty bool) (alg.Algorithm, []byte, error) {
+	parts := strings.SplitN(s, ":", 2)
+	a, err := alg.LookupHash(parts[0])
+	if err != nil {
+		return alg.Algorithm{}, nil, err
+	}
+	if len(parts) == 1 {
+		if !allowEmpty {
+			return alg.Algorithm{}, nil, fmt.Errorf("%q has no digest", s)
+		}
+		return a, nil, nil
+	}
+	digest, err := hex.DecodeString(strings.TrimPrefix(parts[1], "0x"))
+	if err != nil {
+		return alg.Algorithm{}, nil, fmt.Errorf("invalid %v digest: %w", a, err)
+	}
+	if len(digest) != a.DigestSize {
+		return alg.Algorithm{}, nil, fmt.Errorf("%v digest is %d bytes, expected %d bytes", a, len(digest), a.DigestSize)
+	}
+	return a, digest, nil
+}
+
+// parseEvent parses an event for pcr-predict: either a comma-separated list
+// of "<alg>:<hex>" digests, or the name of a file to measure.
+func parseEvent(s string) (pcrs.Event, error) {
+	if i := strings.Index(s, ":"); i > 0 {
+		if _, err := alg.LookupHash(s[:i]); err == nil {
+			digests := make(map[tpm2.Algorithm][]byte)
+			for _, d := range strings.Split(s, ",") {
+				a, digest, err := parseDigest(d, false)
+				if err != nil {
+					return pcrs.Event{}, err
+				}
+				if _, ok := digests[a.ID]; ok {
+					return pcrs.Event{}, fmt.Errorf("%q has more than one %v digest", s, a)
+				}
+				digests[a.ID] = digest
+			}
+			return pcrs.Event{Digests: digests}, nil
+		}
+	}
+	data, err := ioutil.ReadFile(s)
+	if err != nil {
+		return pcrs.Event{}, err
+	}
+	if len(data) > maxEventSize {
+		return pcrs.Event{}, fmt.Errorf("%s is too large for TPM2_PCR_Event (%d bytes, at most %d)", s, len(data), maxEventSize)
+	}
+	return pcrs.Event{Data: data}, nil
+}
+
+func pcrPredict(args []string) int {
+	fs := flag.NewFlagSet("pcr-predict", flag.ContinueOnError)
+	from := fs.String("from", "", "start from the PCR's values in a saved snapshot, or 'live' for the TPM's current values")
+	var starts stringList
+	fs.Var(&starts, "start", "start from the given value in a bank, as <alg>:<hex>, or zero if just <alg> is given (may be repeated)")
+	yaml := fs.Bool("yaml", false, "print the predicted values in the YAML layout of tpm2_pcrread, for use as golden values")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return 1
+	}
+	if len(args) < 2 {
+		fmt.Fprintf(os.Stderr, "'pcr-predict' command expects a PCR index and at least one event: a file, or <alg>:<hex> digests\n")
+		return 1
+	}
+	if *from == "" && len(starts) == 0 {
+		fmt.Fprintf(os.Stderr, "'pcr-predict' command expects --from or --start\n")
+		return 1
+	}
+	pcr, err := strconv.Atoi(args[0])
+	if err != nil || pcr < 0 {
+		fmt.Fprintf(os.Stderr, "Could not parse PCR index %q\n", args[0])
+		return 1
+	}
+	events := make([]pcrs.Event, 0, len(args)-1)
+	for _, arg := range args[1:] {
+		e, err := parseEvent(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid event %q: %v\n", arg, err)
+			return 1
+		}
+		events = append(events, e)
+	}
+
+	// The starting values, with only the one PCR in each bank.
+	start := &pcrs.Snapshot{}
+	if *from != "" {
+		snap, err := loadSnapshot(*from)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not load PCRs from %s: %v\n", *from, err)
+			return 1
+		}
+		for _, bank := range snap.Banks {
+			if value, ok := bank.PCRs[pcr]; ok {
+				start.Banks = append(start.Banks, pcrs.Bank{
+					Hash: bank.Hash,
+					PCRs: map[int][]byte{pcr: value},
+				})
+			}
+		}
+		if len(start.Banks) == 0 {
+			fmt.Fprintf(os.Stderr, "PCR %d is not allocated in any bank of %s\n", pcr, *from)
+			return 1
+		}
+	}
+	for _, s := range starts {
+		a, value, err := parseDigest(s, true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid starting value %q: %v\n", s, err)
+			return 1
+		}
+		if value == nil {
+			value = make([]byte, a.DigestSize)
+		}
+		if bank := start.Bank(a.ID); bank != nil {
+			bank.PCRs[pcr] = value
+			continue
+		}
+		start.Banks = append(start.Banks, pcrs.Bank{
+			Hash: a.ID,
+			PCRs: map[int][]byte{pcr: value},
+		})
+	}
+
+	predicted, err := start.Predict(pcr, events...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not predict the PCR values: %v\n", err)
+		return 1
+	}
+	if *yaml {
+		if err := predicted.WriteYAML(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not write the PCR values: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	for _, bank := range predicted.Banks {
+		fmt.Printf("%s PCR[%02d]: %x\n", alg.Name(bank.Hash), pcr, bank.PCRs[pcr])
+	}
+	return 0
+}
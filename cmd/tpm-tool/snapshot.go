@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/chrisfenner/tpm-top/pkg/pcrs"
+)
+
+func pcrSave(tpm io.ReadWriter, args []string) int {
+	fs := flag.NewFlagSet("pcr-save", flag.ContinueOnError)
+	binary := fs.Bool("binary", false, "save the snapshot in the compact binary format instead of JSON")
+	yaml := fs.Bool("yaml", false, "save the PCR values in the YAML layout of tpm2_pcrread instead of JSON")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return 1
+	}
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "'pcr-save' command expects 1 argument: the file to save the PCRs to, or - for stdout\n")
+		return 1
+	}
+	if *binary && *yaml {
+		fmt.Fprintf(os.Stderr, "only one of --binary and --yaml may be given\n")
+		return 1
+	}
+	snap, err := pcrs.NewReader(tpm).Snapshot()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read the PCRs: %v\n", err)
+		return 1
+	}
+	var data []byte
+	if *binary {
+		data, err = snap.MarshalBinary()
+	} else if *yaml {
+		var buf bytes.Buffer
+		err = snap.WriteYAML(&buf)
+		data = buf.Bytes()
+	} else {
+		data, err = json.MarshalIndent(snap, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not encode the PCRs: %v\n", err)
+		return 1
+	}
+	if args[0] == "-" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := ioutil.WriteFile(args[0], data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not write %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// loadSnapshot reads a saved PCR snapshot, or takes one from the TPM if the
+// name is "live".
+func loadSnapshot(name string) (*pcrs.Snapshot, error) {
+	if name == "live" {
+		tpm, err := openTpm()
+		if err != nil {
+			return nil, fmt.Errorf("could not open TPM simulator: %w", err)
+		}
+		defer tpm.Close()
+		return pcrs.NewReader(tpm).Snapshot()
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return pcrs.ParseSnapshot(data)
+}
+
+// digestString formats a PCR value for a diff.
+func digestString(digest []byte) string {
+	if digest == nil {
+		return "(none)"
+	}
+	return hex.EncodeToString(digest)
+}
+
+func pcrDiff(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "'pcr-diff' command expects 2 arguments: two saved snapshots, or a snapshot and 'live'\n")
+		return 1
+	}
+	snaps := make([]*pcrs.Snapshot, 0, 2)
+	for _, name := range args {
+		snap, err := loadSnapshot(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not load PCRs from %s: %v\n", name, err)
+			return 1
+		}
+		snaps = append(snaps, snap)
+	}
+	changes := pcrs.Diff(snaps[0], snaps[1])
+	for i, prefix := range []string{"---", "+++"} {
+		if snaps[i].Time.IsZero() {
+			// YAML files record only the PCR values.
+			fmt.Printf("%s %s\n", prefix, args[i])
+			continue
+		}
+		fmt.Printf("%s %s (%s, update counter %d)\n", prefix, args[i], snaps[i].Time.Format(time.RFC3339), snaps[i].UpdateCounter)
+	}
+	if len(changes) == 0 {
+		fmt.Printf("No differences.\n")
+		return 0
+	}
+	for _, c := range changes {
+		fmt.Printf("%s PCR[%02d]:\n", alg.Name(c.Hash), c.Index)
+		fmt.Printf("  - %s\n", digestString(c.Old))
+		fmt.Printf("  + %s\n", digestString(c.New))
+	}
+	return 0
+}
+
+func pcrCheck(tpm io.ReadWriter, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "'pcr-check' command expects 1 argument: a file of golden PCR values\n")
+		return 1
+	}
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read %s: %v\n", args[0], err)
+		return 1
+	}
+	golden, err := pcrs.ParseSnapshot(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not load PCRs from %s: %v\n", args[0], err)
+		return 1
+	}
+	snap, err := pcrs.NewReader(tpm).Snapshot()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read the PCRs: %v\n", err)
+		return 1
+	}
+	mismatches := pcrs.Check(golden, snap)
+	for _, c := range mismatches {
+		fmt.Printf("%s PCR[%02d]:\n", alg.Name(c.Hash), c.Index)
+		fmt.Printf("  want %s\n", digestString(c.Old))
+		fmt.Printf("  got  %s\n", digestString(c.New))
+	}
+	if len(mismatches) != 0 {
+		fmt.Printf("%d PCRs do not match the golden values.\n", len(mismatches))
+		return 1
+	}
+	fmt.Printf("All PCRs match the golden values.\n")
+	return 0
+}
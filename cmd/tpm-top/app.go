@@ -0,0 +1,381 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/chrisfenner/tpm-top/pkg/caps"
+	"github.com/chrisfenner/tpm-top/pkg/opener"
+	"github.com/chrisfenner/tpm-top/pkg/vendor"
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// refreshInterval is how often the active view is refreshed.
+const refreshInterval = 1 * time.Second
+
+var (
+	barStyle = ui.Style{
+		Fg:       15,
+		Bg:       236,
+		Modifier: ui.ModifierClear,
+	}
+	barErrorStyle = ui.Style{
+		Fg:       196,
+		Bg:       236,
+		Modifier: ui.ModifierBold,
+	}
+	activeTabStyle = ui.Style{
+		Fg:       234,
+		Bg:       226,
+		Modifier: ui.ModifierBold,
+	}
+	inactiveTabStyle = ui.Style{
+		Fg:       250,
+		Bg:       ui.ColorClear,
+		Modifier: ui.ModifierClear,
+	}
+)
+
+// bar is a one-line widget with text on the left and right, like the header
+// and footer.
+type bar struct {
+	ui.Block
+	left, right string
+	style       ui.Style
+	// rightStyle is the style of the right text.
+	rightStyle ui.Style
+}
+
+func newBar() *bar {
+	return &bar{
+		Block:      *ui.NewBlock(),
+		style:      barStyle,
+		rightStyle: barStyle,
+	}
+}
+
+// Draw implements the termui Drawable interface.
+func (b *bar) Draw(buf *ui.Buffer) {
+	// The bar has no border, so it draws over the whole rectangle.
+	r := b.GetRect()
+	buf.Fill(ui.NewCell(' ', b.style), r)
+	buf.SetString(ui.TrimString(b.left, r.Dx()), b.style, r.Min)
+	right := ui.TrimString(b.right, r.Dx())
+	buf.SetString(right, b.rightStyle, image.Pt(r.Max.X-len([]rune(right)), r.Min.Y))
+}
+
+// tabBar is a one-line widget listing the views, with the active one
+// highlighted.
+type tabBar struct {
+	ui.Block
+	names  []string
+	active int
+}
+
+// Draw implements the termui Drawable interface.
+func (t *tabBar) Draw(buf *ui.Buffer) {
+	r := t.GetRect()
+	x := r.Min.X
+	for i, name := range t.names {
+		style := inactiveTabStyle
+		if i == t.active {
+			style = activeTabStyle
+		}
+		label := fmt.Sprintf(" %d %s ", i+1, name)
+		if i >= 9 {
+			label = fmt.Sprintf(" %s ", name)
+		}
+		buf.SetString(ui.TrimString(label, r.Max.X-x), style, image.Pt(x, r.Min.Y))
+		x += len([]rune(label)) + 1
+		if x >= r.Max.X {
+			return
+		}
+	}
+}
+
+// app is the tpm-top UI: a header with the TPM and the refresh time, a tab
+// for each view, the active view, and a footer with the keys.
+type app struct {
+	conf *opener.TcpConfig
+	log  io.Writer
+	// tpm is kept open between refreshes, and reopened after an error.
+	tpm io.ReadWriteCloser
+	// manufacturer is the vendor of the TPM, from TPM_PT_MANUFACTURER.
+	manufacturer string
+
+	views  []View
+	active int
+	// refreshed is when the active view was last refreshed.
+	refreshed time.Time
+	// err is the error of the last refresh, if any.
+	err error
+
+	// refreshing is the view being refreshed in the background, if any. The
+	// event loop does not draw it or pass it keys until done receives the
+	// result, so that a stalled TPM does not stop the UI from quitting.
+	refreshing View
+	done       chan refreshResult
+	// again is set if a refresh was asked for during the background one.
+	again bool
+	// keys are the keys for the refreshing view, handled when it is done if
+	// the view is still active.
+	keys []queuedKey
+	// resized is set if the terminal was resized during the refresh.
+	resized bool
+
+	header   *bar
+	tabs     *tabBar
+	footer   *bar
+	help     *widgets.Paragraph
+	showHelp bool
+}
+
+func newApp(conf *opener.TcpConfig, log io.Writer) *app {
+	a := &app{
+		conf:   conf,
+		log:    log,
+		views:  views(),
+		header: newBar(),
+		tabs:   &tabBar{Block: *ui.NewBlock()},
+		footer: newBar(),
+		help:   widgets.NewParagraph(),
+		// The result must not block a refresh that ends after run returns.
+		done: make(chan refreshResult, 1),
+	}
+	for _, v := range a.views {
+		a.tabs.names = append(a.tabs.names, v.Name())
+	}
+	a.footer.left = " Tab/Right: next view  Left: previous view  1-9: pick view  ?: help  q: quit"
+	a.help.Title = "Help"
+	a.help.Text = a.helpText()
+	return a
+}
+
+// helpText lists the keys and the views.
+func (a *app) helpText() string {
+	lines := []string{
+		"Tab, Right  next view",
+		"Left        previous view",
+		"1-9         pick a view",
+		"?           show or hide this help",
+		"Esc         hide this help",
+		"q, Ctrl-C   quit",
+		"",
+		"Views:",
+	}
+	for i, v := range a.views {
+		lines = append(lines, fmt.Sprintf("  %d  %s", i+1, v.Name()))
+	}
+	for _, v := range a.views {
+		if k, ok := v.(keyHandler); ok {
+			lines = append(lines, "", fmt.Sprintf("%s view:", v.Name()))
+			lines = append(lines, k.Keys()...)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// layout sizes the widgets to the terminal.
+func (a *app) layout() {
+	width, height := ui.TerminalDimensions()
+	a.header.SetRect(0, 0, width, 1)
+	a.tabs.SetRect(0, 1, width, 2)
+	for _, v := range a.views {
+		v.SetRect(0, 2, width, height-1)
+	}
+	a.footer.SetRect(0, height-1, width, height)
+	helpWidth, helpHeight := 44, strings.Count(a.help.Text, "\n")+3
+	x, y := (width-helpWidth)/2, (height-helpHeight)/2
+	a.help.SetRect(x, y, x+helpWidth, y+helpHeight)
+}
+
+// queuedKey is a key press for a view that was being refreshed.
+type queuedKey struct {
+	view View
+	key  string
+}
+
+// refreshResult is the outcome of a background refresh.
+type refreshResult struct {
+	// tpm is the TPM connection to keep, or nil after an error.
+	tpm io.ReadWriteCloser
+	// connected is set if tpm is a new connection, made by the TPM
+	// manufacturer.
+	connected    bool
+	manufacturer string
+	err          error
+	at           time.Time
+}
+
+// refreshView refreshes the view, opening the TPM if tpm is nil, and closing
+// it after an error. It runs in the background, so it must only touch the view
+// and the TPM.
+func refreshView(conf *opener.TcpConfig, log io.Writer, v View, tpm io.ReadWriteCloser) refreshResult {
+	var result refreshResult
+	if tpm == nil {
+		var err error
+		tpm, err = openTpm(conf, log)
+		if err != nil {
+			result.err = fmt.Errorf("could not open the TPM simulator: %w", err)
+			result.at = time.Now()
+			return result
+		}
+		result.connected = true
+		if id, err := caps.Property(tpm, tpm2.Manufacturer); err == nil {
+			result.manufacturer = vendor.Name(id)
+		}
+	}
+	result.err = v.Refresh(tpm)
+	if result.err != nil {
+		tpm.Close()
+		tpm = nil
+	}
+	result.tpm = tpm
+	result.at = time.Now()
+	return result
+}
+
+// refresh starts refreshing the active view in the background, reconnecting
+// to the TPM if needed. If a refresh is already running, another one follows
+// it.
+func (a *app) refresh() {
+	if a.refreshing != nil {
+		a.again = true
+		return
+	}
+	v, tpm := a.views[a.active], a.tpm
+	a.refreshing = v
+	go func() {
+		a.done <- refreshView(a.conf, a.log, v, tpm)
+	}()
+}
+
+// finishRefresh takes the result of the background refresh, then handles
+// what was put off until it was done.
+func (a *app) finishRefresh(r refreshResult) {
+	a.refreshing = nil
+	a.tpm, a.err, a.refreshed = r.tpm, r.err, r.at
+	if r.connected {
+		a.manufacturer = r.manufacturer
+	}
+	if a.resized {
+		a.resized = false
+		a.layout()
+	}
+	keys := a.keys
+	a.keys = nil
+	for _, k := range keys {
+		// Another view may have been picked since.
+		if k.view == a.views[a.active] {
+			a.handleKey(k.key)
+		}
+	}
+	if a.again {
+		a.again = false
+		a.refresh()
+	}
+}
+
+// render draws the whole UI.
+func (a *app) render() {
+	a.header.left = fmt.Sprintf(" tpm-top  TPM: %s", a.conf.Address)
+	if a.manufacturer != "" {
+		a.header.left += fmt.Sprintf(" (%s)", a.manufacturer)
+	}
+	a.header.right = fmt.Sprintf("refreshed %s ", a.refreshed.Format("15:04:05"))
+	a.header.rightStyle = barStyle
+	if a.err != nil {
+		a.header.right = fmt.Sprintf("error: %v ", a.err)
+		a.header.rightStyle = barErrorStyle
+	}
+	a.tabs.active = a.active
+	ui.Clear()
+	items := []ui.Drawable{a.header, a.tabs, a.views[a.active], a.footer}
+	if a.showHelp {
+		items = append(items, a.help)
+	}
+	ui.Render(items...)
+}
+
+// show makes the view with the given index active, and refreshes it.
+func (a *app) show(index int) {
+	if index < 0 || index >= len(a.views) || index == a.active {
+		return
+	}
+	a.active = index
+	a.refresh()
+}
+
+// handleKey handles a key press. It returns false to quit.
+func (a *app) handleKey(key string) bool {
+	switch key {
+	case "q", "<C-c>":
+		return false
+	case "?":
+		a.showHelp = !a.showHelp
+	case "<Escape>":
+		a.showHelp = false
+	case "<Tab>", "<Right>":
+		a.show((a.active + 1) % len(a.views))
+	case "<Left>":
+		a.show((a.active + len(a.views) - 1) % len(a.views))
+	default:
+		if len(key) == 1 && key[0] >= '1' && key[0] <= '9' {
+			a.show(int(key[0] - '1'))
+		} else if a.views[a.active] == a.refreshing {
+			a.keys = append(a.keys, queuedKey{a.refreshing, key})
+		} else if k, ok := a.views[a.active].(keyHandler); ok {
+			if used, refresh := k.HandleKey(key); used && refresh {
+				a.refresh()
+			}
+		}
+	}
+	return true
+}
+
+// run shows the UI until the user quits.
+func (a *app) run() {
+	defer func() {
+		if a.tpm != nil {
+			a.tpm.Close()
+		}
+	}()
+	a.layout()
+	a.refresh()
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	events := ui.PollEvents()
+	for {
+		select {
+		case e := <-events:
+			switch e.Type {
+			case ui.KeyboardEvent:
+				if !a.handleKey(e.ID) {
+					return
+				}
+			case ui.ResizeEvent:
+				if a.refreshing != nil {
+					a.resized = true
+				} else {
+					a.layout()
+				}
+			}
+		case r := <-a.done:
+			a.finishRefresh(r)
+		case <-ticker.C:
+			// A slow TPM gets one refresh at a time.
+			if a.refreshing == nil {
+				a.refresh()
+			}
+		}
+		// The screen keeps the last frame while the active view refreshes.
+		if a.views[a.active] != a.refreshing {
+			a.render()
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/chrisfenner/tpm-top/pkg/pcrs"
+)
+
+// runBatch prints the PCRs in the YAML layout of tpm2_pcrread once a second,
+// without the UI, each snapshot as its own YAML document. It stops after the
+// given number of iterations, or never if it is 0.
+func runBatch(tpm io.ReadWriter, iterations int) int {
+	reader := pcrs.NewReader(tpm)
+	for i := 0; iterations == 0 || i < iterations; i++ {
+		if i != 0 {
+			time.Sleep(1 * time.Second)
+		}
+		snap, err := reader.Snapshot()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading PCRs: %v\n", err)
+			return 1
+		}
+		fmt.Printf("---\n# %s, update counter %d\n", snap.Time.Format(time.RFC3339), snap.UpdateCounter)
+		if err := snap.WriteYAML(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing PCRs: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
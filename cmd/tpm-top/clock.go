@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/chrisfenner/tpm-top/pkg/clock"
+	ui "github.com/gizak/termui/v3"
+)
+
+const (
+	// maxClockEvents is the number of changes to the boot counters to keep.
+	maxClockEvents = 20
+	// clockJump is how much further Clock may move than the host clock
+	// between two readings before it is taken to have been set
+	// (TPM2_ClockSet), rather than to have drifted.
+	clockJump = time.Second
+	// minDriftTime is how long to measure the drift for before showing its
+	// rate.
+	minDriftTime = 10 * time.Second
+)
+
+// clockSample is a reading of the TPM's clock, with the host time it was
+// taken at.
+type clockSample struct {
+	host time.Time
+	info clock.Info
+}
+
+// clockEvent is a change to the TPM's boot counters or clock.
+type clockEvent struct {
+	at   time.Time
+	what string
+}
+
+// ClockView is a termui widget that shows the TPM's time, clock and boot
+// counters, how far its clock drifts from the host's, and when the boot
+// counters changed.
+type ClockView struct {
+	ui.Block
+	// last is the latest reading.
+	last *clockSample
+	// baseline is the reading the drift is measured from, which is reset
+	// whenever the TPM starts up or its clock is set.
+	baseline *clockSample
+	// events are the changes seen since tpm-top started, newest first. They
+	// are kept across connections, to see what power cycling the TPM did.
+	events []clockEvent
+}
+
+// NewClockView creates a new ClockView.
+func NewClockView() *ClockView {
+	result := &ClockView{
+		Block: *ui.NewBlock(),
+	}
+	result.Block.Title = "Clock and boot counters"
+	return result
+}
+
+// Name implements View.
+func (c *ClockView) Name() string {
+	return "Clock"
+}
+
+// Refresh refreshes the view with new data from the TPM.
+func (c *ClockView) Refresh(tpm io.ReadWriter) error {
+	info, err := clock.Read(tpm)
+	if err != nil {
+		return err
+	}
+	sample := &clockSample{host: time.Now(), info: *info}
+	if c.last != nil {
+		if what := changes(c.last, sample); len(what) != 0 {
+			for _, w := range what {
+				c.events = append([]clockEvent{{at: sample.host, what: w}}, c.events...)
+			}
+			if len(c.events) > maxClockEvents {
+				c.events = c.events[:maxClockEvents]
+			}
+			c.baseline = nil
+		}
+	}
+	if c.baseline == nil {
+		c.baseline = sample
+	}
+	c.last = sample
+	return nil
+}
+
+// changes describes what changed between two readings.
+func changes(before, after *clockSample) []string {
+	b, a := before.info, after.info
+	result := make([]string, 0)
+	switch {
+	case a.ResetCount < b.ResetCount:
+		result = append(result, fmt.Sprintf("TPM2_Clear: reset count %d to %d, restart count %d to %d", b.ResetCount, a.ResetCount, b.RestartCount, a.RestartCount))
+	case a.ResetCount != b.ResetCount:
+		result = append(result, fmt.Sprintf("TPM Reset: reset count %d to %d, restart count %d to %d", b.ResetCount, a.ResetCount, b.RestartCount, a.RestartCount))
+	case a.RestartCount != b.RestartCount:
+		result = append(result, fmt.Sprintf("TPM Restart or Resume: restart count %d to %d", b.RestartCount, a.RestartCount))
+	case a.Time < b.Time:
+		result = append(result, "Time went back without a change to the boot counters")
+	}
+	host := after.host.Sub(before.host)
+	tpm := milliseconds(a.Clock) - milliseconds(b.Clock)
+	switch {
+	case a.Clock < b.Clock:
+		result = append(result, fmt.Sprintf("Clock went back by %v", -tpm))
+	case tpm > host+clockJump:
+		result = append(result, fmt.Sprintf("Clock was set forward by about %v", (tpm-host).Round(time.Second)))
+	}
+	if a.Safe != b.Safe {
+		result = append(result, fmt.Sprintf("Safe changed to %s", yesNo(a.Safe)))
+	}
+	return result
+}
+
+// milliseconds converts a time in milliseconds to a Duration.
+func milliseconds(ms uint64) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
+
+// Draw implements the termui Drawable interface.
+func (c *ClockView) Draw(buf *ui.Buffer) {
+	c.Block.Draw(buf)
+	if c.last == nil {
+		return
+	}
+	info := c.last.info
+
+	now := section{title: "TPM2_ReadClock"}
+	now.add("Time", "%d ms (%v)", info.Time, milliseconds(info.Time).Truncate(time.Second))
+	now.add("Powered on at", "%s", c.last.host.Add(-milliseconds(info.Time)).Format("2006-01-02 15:04:05"))
+	now.add("Clock", "%d ms (%v)", info.Clock, milliseconds(info.Clock).Truncate(time.Second))
+	now.add("Reset count", "%d", info.ResetCount)
+	now.add("Restart count", "%d", info.RestartCount)
+	if info.Safe {
+		now.add("Safe", "yes")
+	} else {
+		now.addStyled("Safe", warnStyle, "no")
+	}
+
+	drift := section{title: "Drift from the host clock"}
+	host := c.last.host.Sub(c.baseline.host)
+	tpm := milliseconds(info.Clock) - milliseconds(c.baseline.info.Clock)
+	drift.add("Since", "%s", c.baseline.host.Format("15:04:05"))
+	drift.add("Host elapsed", "%v", host.Round(time.Millisecond))
+	drift.add("Clock elapsed", "%v", tpm)
+	drift.add("Drift", "%+.1f ms", float64(tpm-host)/float64(time.Millisecond))
+	if host < minDriftTime {
+		drift.addStyled("Rate", dimStyle, "measuring")
+	} else {
+		drift.add("Rate", "%+.0f ppm", float64(tpm-host)/float64(host)*1e6)
+	}
+
+	events := section{title: "Changes seen"}
+	if len(c.events) == 0 {
+		events.addStyled("", dimStyle, "none yet")
+	}
+	for _, e := range c.events {
+		events.add(e.at.Format("15:04:05"), "%s", e.what)
+	}
+
+	drawSections(buf, c.Block.Inner, []section{now, drift, events})
+}
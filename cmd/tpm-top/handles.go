@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/chrisfenner/tpm-top/pkg/caps"
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+const (
+	// gaugeWidth and gaugeHeight are the size of each resource gauge.
+	gaugeWidth  = 40
+	gaugeHeight = 3
+)
+
+const (
+	// htNVIndex, htTransient and htPersistent are the handle types
+	// (TPM_HT) of the handles to list.
+	htNVIndex    tpm2.TPMProp = 0x01000000
+	htTransient  tpm2.TPMProp = 0x80000000
+	htPersistent tpm2.TPMProp = 0x81000000
+	// htPolicySession is the type of a policy session handle.
+	htPolicySession = 0x03
+)
+
+// curveNames are the names of the TPM_ECC_CURVE values.
+var curveNames = map[tpm2.EllipticCurve]string{
+	tpm2.CurveNISTP192: "NIST P-192",
+	tpm2.CurveNISTP224: "NIST P-224",
+	tpm2.CurveNISTP256: "NIST P-256",
+	tpm2.CurveNISTP384: "NIST P-384",
+	tpm2.CurveNISTP521: "NIST P-521",
+	tpm2.CurveBNP256:   "BN P-256",
+	tpm2.CurveBNP638:   "BN P-638",
+	tpm2.CurveSM2P256:  "SM2 P-256",
+}
+
+// usage is how many of a TPM resource are used, out of how many there are.
+type usage struct {
+	name  string
+	used  uint32
+	total uint32
+}
+
+// HandlesView is a termui widget that shows the TPM's loaded objects,
+// sessions and NV indices, and gauges of how many more it has room for.
+type HandlesView struct {
+	ui.Block
+	usages   []usage
+	sections []section
+	// contextGap is TPM_PT_CONTEXT_GAP_MAX.
+	contextGap uint32
+}
+
+// NewHandlesView creates a new HandlesView.
+func NewHandlesView() *HandlesView {
+	result := &HandlesView{
+		Block: *ui.NewBlock(),
+	}
+	result.Block.Title = "Handles and resources"
+	return result
+}
+
+// Name implements View.
+func (h *HandlesView) Name() string {
+	return "Handles"
+}
+
+// Refresh refreshes the view with new data from the TPM.
+func (h *HandlesView) Refresh(tpm io.ReadWriter) error {
+	v, err := caps.Properties(tpm, ptVar)
+	if err != nil {
+		return err
+	}
+	contextGap, err := caps.Property(tpm, tpm2.ContextGapMax)
+	if err != nil {
+		return err
+	}
+	transient, err := caps.Handles(tpm, htTransient)
+	if err != nil {
+		return err
+	}
+	persistent, err := caps.Handles(tpm, htPersistent)
+	if err != nil {
+		return err
+	}
+	loaded, err := caps.Sessions(tpm, false)
+	if err != nil {
+		return err
+	}
+	saved, err := caps.Sessions(tpm, true)
+	if err != nil {
+		return err
+	}
+	nv, err := caps.Handles(tpm, htNVIndex)
+	if err != nil {
+		return err
+	}
+
+	h.contextGap = contextGap
+	h.usages = []usage{
+		{"Transient objects", uint32(len(transient)), uint32(len(transient)) + v[tpm2.HRTransientAvail]},
+		{"Persistent objects", v[tpm2.CurrentPersistent], v[tpm2.CurrentPersistent] + v[tpm2.AvailPersistent]},
+		{"Loaded sessions", v[tpm2.HRLoaded], v[tpm2.HRLoaded] + v[tpm2.HRLoadedAvail]},
+		{"Active sessions", v[tpm2.HRActive], v[tpm2.HRActive] + v[tpm2.HRActiveAvail]},
+		{"NV counters", v[tpm2.NVCounters], v[tpm2.NVCounters] + v[tpm2.NVCountersAvail]},
+	}
+	h.sections = []section{
+		objectSection(tpm, "Transient objects", transient),
+		objectSection(tpm, "Persistent objects", persistent),
+		sessionSection("Loaded sessions", loaded, false),
+		sessionSection("Saved sessions", saved, true),
+		nvSection(tpm, nv),
+	}
+	return nil
+}
+
+// objectSection lists the objects with their types.
+func objectSection(tpm io.ReadWriter, title string, handles []tpmutil.Handle) section {
+	s := section{title: fmt.Sprintf("%s (%d)", title, len(handles))}
+	for _, handle := range handles {
+		pub, _, _, err := tpm2.ReadPublic(tpm, handle)
+		if err != nil {
+			s.addStyled(handleString(handle), warnStyle, "%v", err)
+			continue
+		}
+		s.add(handleString(handle), "%s", objectType(pub))
+	}
+	return s
+}
+
+// objectType describes the type of an object, e.g. "RSA-2048, SHA2-256
+// name".
+func objectType(pub tpm2.Public) string {
+	kind := alg.Name(pub.Type)
+	switch {
+	case pub.RSAParameters != nil:
+		kind = fmt.Sprintf("%s-%d", kind, pub.RSAParameters.KeyBits)
+	case pub.ECCParameters != nil:
+		curve, ok := curveNames[pub.ECCParameters.CurveID]
+		if !ok {
+			curve = fmt.Sprintf("curve 0x%04x", pub.ECCParameters.CurveID)
+		}
+		kind = fmt.Sprintf("%s %s", kind, curve)
+	}
+	return fmt.Sprintf("%s, %s name", kind, alg.Name(pub.NameAlg))
+}
+
+// sessionSection lists the sessions with their types. The TPM may list saved
+// sessions with HMAC session handles whatever their type.
+func sessionSection(title string, handles []tpmutil.Handle, saved bool) section {
+	s := section{title: fmt.Sprintf("%s (%d)", title, len(handles))}
+	for _, handle := range handles {
+		if saved {
+			s.addStyled(handleString(handle), dimStyle, "saved")
+		} else if handle>>24 == htPolicySession {
+			s.add(handleString(handle), "policy")
+		} else {
+			s.add(handleString(handle), "HMAC")
+		}
+	}
+	return s
+}
+
+// nvSection lists the NV indices with their sizes.
+func nvSection(tpm io.ReadWriter, handles []tpmutil.Handle) section {
+	s := section{title: fmt.Sprintf("NV indices (%d)", len(handles))}
+	for _, handle := range handles {
+		pub, err := tpm2.NVReadPublic(tpm, handle)
+		if err != nil {
+			s.addStyled(handleString(handle), warnStyle, "%v", err)
+			continue
+		}
+		s.add(handleString(handle), "%d bytes", pub.DataSize)
+	}
+	return s
+}
+
+// handleString formats a handle.
+func handleString(handle tpmutil.Handle) string {
+	return fmt.Sprintf("0x%08x", uint32(handle))
+}
+
+// gauge makes a gauge of the usage, which turns yellow when the resource is
+// nearly used up and red when it is.
+func (u *usage) gauge() *widgets.Gauge {
+	g := widgets.NewGauge()
+	g.Title = u.name
+	g.BarColor = ui.ColorGreen
+	if u.total != 0 {
+		g.Percent = int(100 * u.used / u.total)
+	}
+	switch {
+	case u.total != 0 && u.used >= u.total:
+		g.BarColor = ui.ColorRed
+	case g.Percent >= 75:
+		g.BarColor = ui.ColorYellow
+	}
+	g.Label = fmt.Sprintf("%d of %d", u.used, u.total)
+	return g
+}
+
+// Draw implements the termui Drawable interface.
+func (h *HandlesView) Draw(buf *ui.Buffer) {
+	h.Block.Draw(buf)
+	inner := h.Block.Inner
+	y := inner.Min.Y
+	for _, u := range h.usages {
+		if y+gaugeHeight > inner.Max.Y {
+			break
+		}
+		g := u.gauge()
+		g.SetRect(inner.Min.X, y, inner.Min.X+gaugeWidth, y+gaugeHeight)
+		g.Draw(buf)
+		y += gaugeHeight
+	}
+	// The TPM does not report the current context gap, only its limit.
+	note := section{title: "Saved contexts"}
+	note.add("Context gap", "at most %d", h.contextGap)
+	drawSections(buf, image.Rect(inner.Min.X, y+1, inner.Min.X+gaugeWidth, inner.Max.Y), []section{note})
+
+	drawSections(buf, image.Rect(inner.Min.X+gaugeWidth+sectionGap, inner.Min.Y, inner.Max.X, inner.Max.Y), h.sections)
+}
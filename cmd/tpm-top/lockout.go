@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"time"
+
+	"github.com/chrisfenner/tpm-top/pkg/caps"
+	"github.com/chrisfenner/tpm-top/pkg/clock"
+	ui "github.com/gizak/termui/v3"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// inLockout is the TPMA_PERMANENT bit that is set when the TPM is in DA lockout.
+const inLockout = 1 << 9
+
+// attrBit is a bit of a TPMA_PERMANENT or TPMA_STARTUP_CLEAR.
+type attrBit struct {
+	bit   uint32
+	label string
+	// set and clear describe the bit's values.
+	set, clear string
+	// warn is set if the bit needs attention when it is set.
+	warn bool
+}
+
+// permanentFlags are the bits of TPMA_PERMANENT.
+var permanentFlags = []attrBit{
+	{1 << 0, "ownerAuthSet", "yes", "no", false},
+	{1 << 1, "endorsementAuthSet", "yes", "no", false},
+	{1 << 2, "lockoutAuthSet", "yes", "no", false},
+	{1 << 8, "disableClear", "yes", "no", false},
+	{inLockout, "inLockout", "yes", "no", true},
+	{1 << 10, "tpmGeneratedEPS", "yes", "no", false},
+}
+
+// startupClearFlags are the bits of TPMA_STARTUP_CLEAR.
+var startupClearFlags = []attrBit{
+	{1 << 0, "phEnable", "enabled", "disabled", false},
+	{1 << 1, "shEnable", "enabled", "disabled", false},
+	{1 << 2, "ehEnable", "enabled", "disabled", false},
+	{1 << 3, "phEnableNV", "enabled", "disabled", false},
+	{1 << 31, "orderly", "yes", "no", false},
+}
+
+// LockoutView is a termui widget that shows the TPM's dictionary attack
+// protection state, with an estimate of when the failure counter next
+// decrements, and which hierarchies are enabled and have authorization
+// values.
+type LockoutView struct {
+	ui.Block
+	permanent, startupClear uint32
+	// counter, maxTries, interval and recovery are TPM_PT_LOCKOUT_COUNTER,
+	// TPM_PT_MAX_AUTH_FAIL, TPM_PT_LOCKOUT_INTERVAL and
+	// TPM_PT_LOCKOUT_RECOVERY.
+	counter, maxTries, interval, recovery uint32
+	// poweredOn is when the TPM was last powered on, by the host clock.
+	poweredOn time.Time
+	// healFrom is when the TPM was last seen to start counting towards the
+	// next decrement of the failure counter: when the counter last changed,
+	// or the TPM was powered on. It is zero until then, since failures
+	// before tpm-top started restart the count without a trace.
+	healFrom time.Time
+	// seen is set once the view has read the counter.
+	seen bool
+}
+
+// NewLockoutView creates a new LockoutView.
+func NewLockoutView() *LockoutView {
+	result := &LockoutView{
+		Block: *ui.NewBlock(),
+	}
+	result.Block.Title = "Dictionary attack protection and hierarchies"
+	return result
+}
+
+// Name implements View.
+func (l *LockoutView) Name() string {
+	return "Lockout"
+}
+
+// Refresh refreshes the view with new data from the TPM.
+func (l *LockoutView) Refresh(tpm io.ReadWriter) error {
+	v, err := caps.Properties(tpm, ptVar)
+	if err != nil {
+		return err
+	}
+	info, err := clock.Read(tpm)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	poweredOn := now.Add(-milliseconds(info.Time))
+	// The TPM counts towards the next decrement while it is powered on, so
+	// powering it on restarts the count. The time reading jitters a little.
+	if l.seen && poweredOn.Sub(l.poweredOn) > time.Second {
+		l.healFrom = poweredOn
+	}
+	// A failure restarts the count, and so does a decrement, as far as the
+	// next decrement is concerned.
+	if l.seen && v[tpm2.LockoutCounter] != l.counter {
+		l.healFrom = now
+	}
+	l.poweredOn = poweredOn
+	l.permanent = v[tpm2.TPMAPermanent]
+	l.startupClear = v[tpm2.TPMAStartupClear]
+	l.counter = v[tpm2.LockoutCounter]
+	l.maxTries = v[tpm2.MaxAuthFail]
+	l.interval = v[tpm2.LockoutInterval]
+	l.recovery = v[tpm2.LockoutRecovery]
+	l.seen = true
+	return nil
+}
+
+// flagSection describes the bits of a TPMA_PERMANENT or TPMA_STARTUP_CLEAR.
+func flagSection(title string, value uint32, flags []attrBit) section {
+	s := section{title: fmt.Sprintf("%s (0x%08x)", title, value)}
+	for _, f := range flags {
+		switch {
+		case value&f.bit == 0:
+			s.add(f.label, "%s", f.clear)
+		case f.warn:
+			s.addStyled(f.label, warnStyle, "%s", f.set)
+		default:
+			s.add(f.label, "%s", f.set)
+		}
+	}
+	return s
+}
+
+// seconds formats a number of seconds, e.g. "1000 s (16m40s)".
+func seconds(s uint32) string {
+	d := time.Duration(s) * time.Second
+	if d < time.Minute {
+		return fmt.Sprintf("%d s", s)
+	}
+	return fmt.Sprintf("%d s (%v)", s, d)
+}
+
+// nextDecrement describes when the failure counter next decrements.
+func (l *LockoutView) nextDecrement(now time.Time) (string, ui.Style) {
+	interval := time.Duration(l.interval) * time.Second
+	switch {
+	case l.counter == 0:
+		return "no failures to forgive", dimStyle
+	case l.interval == 0:
+		return "never, protection is disabled", dimStyle
+	case l.healFrom.IsZero():
+		return fmt.Sprintf("in at most %v", interval), valueStyle
+	}
+	left := l.healFrom.Add(interval).Sub(now).Round(time.Second)
+	if left <= 0 {
+		return "due now", valueStyle
+	}
+	return fmt.Sprintf("in about %v", left), valueStyle
+}
+
+// daSection describes the dictionary attack protection.
+func (l *LockoutView) daSection(now time.Time) section {
+	s := section{title: "Dictionary attack protection"}
+	switch {
+	case l.permanent&inLockout != 0:
+		s.addStyled("State", warnStyle, "in lockout")
+	case l.counter >= l.maxTries:
+		s.addStyled("State", warnStyle, "no more failures allowed")
+	case l.maxTries-l.counter == 1:
+		s.add("State", "1 more failure allowed")
+	default:
+		s.add("State", "%d more failures allowed", l.maxTries-l.counter)
+	}
+	next, style := l.nextDecrement(now)
+	s.addStyled("Next decrement", style, "%s", next)
+	if l.counter > 1 && l.interval != 0 {
+		s.add("All forgiven", "%v after that", time.Duration(l.counter-1)*time.Duration(l.interval)*time.Second)
+	}
+	if l.interval == 0 {
+		s.addStyled("Lockout interval", dimStyle, "0 (protection is disabled)")
+	} else {
+		s.add("Lockout interval", "%s", seconds(l.interval))
+	}
+	if l.recovery == 0 {
+		s.add("Lockout recovery", "0 (lockout authorization waits for a TPM Reset after a failure)")
+	} else {
+		s.add("Lockout recovery", "%s", seconds(l.recovery))
+	}
+	s.add("Powered on at", "%s", l.poweredOn.Format("2006-01-02 15:04:05"))
+	return s
+}
+
+// Draw implements the termui Drawable interface.
+func (l *LockoutView) Draw(buf *ui.Buffer) {
+	l.Block.Draw(buf)
+	if !l.seen {
+		return
+	}
+	inner := l.Block.Inner
+	failures := usage{"Failures", l.counter, l.maxTries}
+	g := failures.gauge()
+	g.SetRect(inner.Min.X, inner.Min.Y, inner.Min.X+gaugeWidth, inner.Min.Y+gaugeHeight)
+	g.Draw(buf)
+	drawSections(buf, image.Rect(inner.Min.X, inner.Min.Y+gaugeHeight+1, inner.Max.X, inner.Max.Y), []section{
+		l.daSection(time.Now()),
+		flagSection("TPMA_PERMANENT", l.permanent, permanentFlags),
+		flagSection("TPMA_STARTUP_CLEAR", l.startupClear, startupClearFlags),
+	})
+}
@@ -1,44 +1,71 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
-	"time"
 
 	"github.com/chrisfenner/tpm-top/pkg/opener"
+	"github.com/chrisfenner/tpm-top/pkg/trace"
 	ui "github.com/gizak/termui/v3"
 )
 
+var (
+	traceFile  = flag.String("trace", "", "log every TPM command and response to the given file")
+	batch      = flag.Bool("b", false, "batch mode: print the PCRs in the tpm2_pcrread YAML layout instead of showing the UI")
+	iterations = flag.Int("n", 0, "in batch mode, the number of times to print the PCRs (0 for no limit)")
+)
+
+// openTpm opens a connection to the TPM simulator, tracing it if requested.
+func openTpm(conf *opener.TcpConfig, log io.Writer) (io.ReadWriteCloser, error) {
+	tpm, err := opener.OpenTcpTpm(conf)
+	if err != nil {
+		return nil, err
+	}
+	if log != nil {
+		tpm = trace.New(tpm, log)
+	}
+	return tpm, nil
+}
+
 func main() {
+	flag.Parse()
+	os.Exit(run())
+}
+
+// run runs tpm-top and returns the exit code, so that deferred calls run
+// before the process exits.
+func run() int {
 	conf := opener.TcpConfig{
 		Address: "127.0.0.1:2321",
 	}
-
-	if err := ui.Init(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error initializing termui: %v\n", err)
+	var log io.Writer
+	if *traceFile != "" {
+		f, err := os.Create(*traceFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating trace file: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		log = f
 	}
-	defer ui.Close()
 
-	pcrView := NewPcrView()
-	go func() {
-		for true {
-			width, height := ui.TerminalDimensions()
-			pcrView.SetRect(0, 0, width, height)
-			tpm, err := opener.OpenTcpTpm(&conf)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error opening TPM simulator: %v\n", err)
-				return
-			}
-			pcrView.Refresh(tpm)
-			tpm.Close()
-			ui.Render(pcrView)
-			time.Sleep(1 * time.Second)
+	if *batch {
+		tpm, err := openTpm(&conf, log)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening TPM simulator: %v\n", err)
+			return 1
 		}
-	}()
+		defer tpm.Close()
+		return runBatch(tpm, *iterations)
+	}
 
-	for e := range ui.PollEvents() {
-		if e.Type == ui.KeyboardEvent {
-			break
-		}
+	if err := ui.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing termui: %v\n", err)
+		return 1
 	}
+	defer ui.Close()
+	newApp(&conf, log).run()
+	return 0
 }
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/chrisfenner/tpm-top/pkg/caps"
+	"github.com/chrisfenner/tpm-top/pkg/nv"
+	ui "github.com/gizak/termui/v3"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+const (
+	// nvListWidth is the width of the list of NV indices.
+	nvListWidth = 44
+	// dumpBytes is the number of bytes on each line of the hex dump.
+	dumpBytes = 16
+	// attributesPerRow is the number of attribute names on each row of the
+	// details.
+	attributesPerRow = 4
+)
+
+var selectedStyle = ui.Style{
+	Fg:       234,
+	Bg:       14,
+	Modifier: ui.ModifierBold,
+}
+
+// errNotRead is shown for an index that is only read when asked to, since a
+// failed read counts towards dictionary attack lockout.
+var errNotRead = errors.New("reading this index may count towards dictionary attack lockout; press r to try the empty password")
+
+// nvIndex is an NV index in the NVView.
+type nvIndex struct {
+	pub tpm2.NVPublic
+	// value is the decoded value of a counter, bit field or PIN index, if
+	// it could be read.
+	value string
+}
+
+// NVView is a termui widget that lists the NV indices, with the public area
+// and contents of the selected one.
+type NVView struct {
+	ui.Block
+	indices  []nvIndex
+	selected int
+	// contents are the contents of the selected index, or contentsErr why
+	// they could not be read.
+	contents    []byte
+	contentsErr error
+	// scroll is the first line of the hex dump to show.
+	scroll int
+
+	// tpm is the TPM connection that blockSize is for.
+	tpm       io.ReadWriter
+	blockSize int
+	// allowed are the indices whose failed reads count towards dictionary
+	// attack lockout that the user asked to read. failed remembers the ones
+	// that could not be read, so that they are not tried again until the
+	// user asks again or the index changes. Both are kept across
+	// reconnections.
+	allowed map[tpmutil.Handle]bool
+	failed  map[tpmutil.Handle]error
+}
+
+// NewNVView creates a new NVView.
+func NewNVView() *NVView {
+	result := &NVView{
+		Block:   *ui.NewBlock(),
+		allowed: make(map[tpmutil.Handle]bool),
+		failed:  make(map[tpmutil.Handle]error),
+	}
+	result.Block.Title = "NV indices"
+	return result
+}
+
+// Name implements View.
+func (n *NVView) Name() string {
+	return "NV"
+}
+
+// Keys implements keyHandler.
+func (n *NVView) Keys() []string {
+	return []string{
+		"Up, Down    pick an NV index",
+		"PgUp, PgDn  scroll the contents",
+		"Home, End   go to the start or end",
+		"r           read it with the empty password",
+	}
+}
+
+// HandleKey implements keyHandler. Only picking an index or reading it
+// needs data from the TPM.
+func (n *NVView) HandleKey(key string) (bool, bool) {
+	switch key {
+	case "<Up>":
+		if n.selected > 0 {
+			n.selected--
+			n.scroll = 0
+		}
+		return true, true
+	case "<Down>":
+		if n.selected < len(n.indices)-1 {
+			n.selected++
+			n.scroll = 0
+		}
+		return true, true
+	case "r":
+		if n.selected >= 0 && n.selected < len(n.indices) {
+			handle := n.indices[n.selected].pub.NVIndex
+			n.allowed[handle] = true
+			delete(n.failed, handle)
+		}
+		return true, true
+	case "<PageUp>":
+		n.scroll -= n.Block.Inner.Dy() / 2
+	case "<PageDown>":
+		n.scroll += n.Block.Inner.Dy() / 2
+	case "<Home>":
+		n.scroll = 0
+	case "<End>":
+		// Draw stops at the last line.
+		n.scroll = len(n.contents)
+	default:
+		return false, false
+	}
+	return true, false
+}
+
+// read reads the contents of the index. An index whose failed reads count
+// towards dictionary attack lockout is only read if the user asked to, and
+// not again after it failed.
+func (n *NVView) read(tpm io.ReadWriter, pub tpm2.NVPublic) ([]byte, error) {
+	if !nv.CountsFailures(pub) {
+		return nv.Read(tpm, pub, n.blockSize)
+	}
+	if !n.allowed[pub.NVIndex] {
+		return nil, errNotRead
+	}
+	if err, ok := n.failed[pub.NVIndex]; ok {
+		return nil, err
+	}
+	data, err := nv.Read(tpm, pub, n.blockSize)
+	if err != nil {
+		n.failed[pub.NVIndex] = err
+	}
+	return data, err
+}
+
+// Refresh refreshes the view with new data from the TPM.
+func (n *NVView) Refresh(tpm io.ReadWriter) error {
+	if tpm != n.tpm {
+		blockSize, err := caps.Property(tpm, tpm2.NVMaxBufferSize)
+		if err != nil {
+			return err
+		}
+		n.tpm = tpm
+		n.blockSize = int(blockSize)
+	}
+	handles, err := caps.Handles(tpm, htNVIndex)
+	if err != nil {
+		return err
+	}
+	previous := make(map[tpmutil.Handle]tpm2.NVAttr, len(n.indices))
+	for _, index := range n.indices {
+		previous[index.pub.NVIndex] = index.pub.Attributes
+	}
+	indices := make([]nvIndex, 0, len(handles))
+	for _, handle := range handles {
+		pub, err := tpm2.NVReadPublic(tpm, handle)
+		if err != nil {
+			return fmt.Errorf("could not read the public area of NV index 0x%08x: %w", uint32(handle), err)
+		}
+		// Try again if the index changed, e.g. it was written or unlocked.
+		if attrs, ok := previous[handle]; !ok || attrs != pub.Attributes {
+			delete(n.failed, handle)
+		}
+		index := nvIndex{pub: pub}
+		if t := nv.TypeOf(pub.Attributes); t != nv.TypeOrdinary && t != nv.TypeExtend {
+			if data, err := n.read(tpm, pub); err == nil {
+				index.value, _ = nv.Value(t, data)
+			}
+		}
+		indices = append(indices, index)
+	}
+	n.indices = indices
+	// Forget the indices that were undefined, in case they are defined again
+	// with another authorization value.
+	defined := make(map[tpmutil.Handle]bool, len(handles))
+	for _, handle := range handles {
+		defined[handle] = true
+	}
+	for handle := range n.allowed {
+		if !defined[handle] {
+			delete(n.allowed, handle)
+		}
+	}
+	for handle := range n.failed {
+		if !defined[handle] {
+			delete(n.failed, handle)
+		}
+	}
+	if n.selected >= len(n.indices) {
+		n.selected = len(n.indices) - 1
+	}
+	if n.selected < 0 && len(n.indices) > 0 {
+		n.selected = 0
+	}
+	n.contents, n.contentsErr = nil, nil
+	if n.selected >= 0 {
+		n.contents, n.contentsErr = n.read(tpm, n.indices[n.selected].pub)
+	}
+	return nil
+}
+
+// listLine describes an index in the list.
+func (i *nvIndex) listLine() string {
+	line := fmt.Sprintf("0x%08x  %-8s %5d B", uint32(i.pub.NVIndex), nv.TypeOf(i.pub.Attributes), i.pub.DataSize)
+	if i.value != "" {
+		line += "  " + i.value
+	}
+	return line
+}
+
+// details describes the public area and state of the index.
+func (i *nvIndex) details() section {
+	s := section{title: fmt.Sprintf("NV index 0x%08x", uint32(i.pub.NVIndex))}
+	t := nv.TypeOf(i.pub.Attributes)
+	s.add("Type", "%s", t)
+	if i.value != "" {
+		s.add("Value", "%s", i.value)
+	}
+	s.add("Size", "%d bytes", i.pub.DataSize)
+	s.add("Name algorithm", "%s", alg.Name(i.pub.NameAlg))
+	if len(i.pub.AuthPolicy) == 0 {
+		s.addStyled("Policy", dimStyle, "none")
+	} else {
+		s.add("Policy", "%s", hex.EncodeToString(i.pub.AuthPolicy))
+	}
+	names := nv.AttributeNames(i.pub.Attributes)
+	for j := 0; j < len(names); j += attributesPerRow {
+		label := ""
+		if j == 0 {
+			label = "Attributes"
+		}
+		end := j + attributesPerRow
+		if end > len(names) {
+			end = len(names)
+		}
+		s.add(label, "%s", strings.Join(names[j:end], " "))
+	}
+	s.add("Written", "%s", yesNo(i.pub.Attributes&tpm2.AttrWritten != 0))
+	s.add("Write-locked", "%s", yesNo(i.pub.Attributes&tpm2.AttrWriteLocked != 0))
+	s.add("Read-locked", "%s", yesNo(i.pub.Attributes&tpm2.AttrReadLocked != 0))
+	return s
+}
+
+// yesNo formats a flag.
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// dumpLines formats data as a hex and ASCII dump.
+func dumpLines(data []byte) []string {
+	result := make([]string, 0, (len(data)+dumpBytes-1)/dumpBytes)
+	for offset := 0; offset < len(data); offset += dumpBytes {
+		end := offset + dumpBytes
+		if end > len(data) {
+			end = len(data)
+		}
+		line := data[offset:end]
+		hexPart := make([]string, len(line))
+		ascii := make([]byte, len(line))
+		for j, b := range line {
+			hexPart[j] = fmt.Sprintf("%02x", b)
+			ascii[j] = '.'
+			if b >= 0x20 && b < 0x7f {
+				ascii[j] = b
+			}
+		}
+		result = append(result, fmt.Sprintf("%-*s  |%s|", 3*dumpBytes-1, strings.Join(hexPart, " "), ascii))
+	}
+	return result
+}
+
+// Draw implements the termui Drawable interface.
+func (n *NVView) Draw(buf *ui.Buffer) {
+	n.Block.Draw(buf)
+	inner := n.Block.Inner
+	if len(n.indices) == 0 {
+		buf.SetString("No NV indices are defined.", dimStyle, inner.Min)
+		return
+	}
+
+	// The list of indices, scrolled to keep the selected one in view.
+	first := 0
+	if n.selected >= inner.Dy() {
+		first = n.selected - inner.Dy() + 1
+	}
+	for i := first; i < len(n.indices) && i-first < inner.Dy(); i++ {
+		style := valueStyle
+		if i == n.selected {
+			style = selectedStyle
+		}
+		line := fmt.Sprintf("%-*s", nvListWidth, n.indices[i].listLine())
+		buf.SetString(ui.TrimString(line, nvListWidth), style, image.Pt(inner.Min.X, inner.Min.Y+i-first))
+	}
+
+	// The details of the selected index, then its contents.
+	right := image.Rect(inner.Min.X+nvListWidth+sectionGap, inner.Min.Y, inner.Max.X, inner.Max.Y)
+	details := n.indices[n.selected].details()
+	drawSections(buf, right, []section{details})
+	y := right.Min.Y + details.height() + 1
+	if y >= right.Max.Y {
+		return
+	}
+	if n.contentsErr != nil {
+		buf.SetString("Contents", sectionTitleStyle, image.Pt(right.Min.X, y))
+		buf.SetString(ui.TrimString(n.contentsErr.Error(), right.Dx()), dimStyle, image.Pt(right.Min.X, y+1))
+		return
+	}
+	lines := dumpLines(n.contents)
+	visible := right.Max.Y - y - 1
+	if n.scroll > len(lines)-visible {
+		n.scroll = len(lines) - visible
+	}
+	if n.scroll < 0 {
+		n.scroll = 0
+	}
+	title := "Contents"
+	if len(lines) > visible {
+		title = fmt.Sprintf("Contents (lines %d-%d of %d)", n.scroll+1, n.scroll+visible, len(lines))
+	}
+	buf.SetString(title, sectionTitleStyle, image.Pt(right.Min.X, y))
+	for i := 0; i < visible && n.scroll+i < len(lines); i++ {
+		offset := fmt.Sprintf("%04x  ", (n.scroll+i)*dumpBytes)
+		buf.SetString(offset, dimStyle, image.Pt(right.Min.X, y+1+i))
+		buf.SetString(ui.TrimString(lines[n.scroll+i], right.Dx()-len(offset)), valueStyle, image.Pt(right.Min.X+len(offset), y+1+i))
+	}
+}
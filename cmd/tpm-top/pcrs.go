@@ -6,6 +6,7 @@ import (
 	"image"
 	"io"
 
+	"github.com/chrisfenner/tpm-top/pkg/alg"
 	"github.com/chrisfenner/tpm-top/pkg/pcrs"
 	ui "github.com/gizak/termui/v3"
 	"github.com/google/go-tpm/tpm2"
@@ -27,6 +28,11 @@ var (
 		Bg:       ui.ColorClear,
 		Modifier: ui.ModifierBold,
 	}
+	pcrNoteStyle = ui.Style{
+		Fg:       244,
+		Bg:       ui.ColorClear,
+		Modifier: ui.ModifierClear,
+	}
 	paddingStyle = ui.Style{
 		Fg:       ui.ColorClear,
 		Bg:       ui.ColorClear,
@@ -38,6 +44,10 @@ var (
 type PcrView struct {
 	ui.Block
 	pcrs []pcrData
+	// tpm is the TPM connection that reader reads from.
+	tpm io.ReadWriter
+	// reader caches the PCR layout between refreshes.
+	reader *pcrs.Reader
 }
 
 // NewPcrView creates a new PcrView.
@@ -49,41 +59,58 @@ func NewPcrView() *PcrView {
 	return result
 }
 
+// Name implements View.
+func (p *PcrView) Name() string {
+	return "PCRs"
+}
+
 // Refresh refreshes the view with new data from the TPM.
-func (p *PcrView) Refresh(tpm io.ReadWriter) {
-	pcrBanks := make([]pcrData, 0)
-	// Find out which PCRs are implemented.
-	algs, err := pcrs.GetAlgorithms(tpm)
+func (p *PcrView) Refresh(tpm io.ReadWriter) error {
+	if tpm != p.tpm {
+		p.tpm = tpm
+		p.reader = pcrs.NewReader(tpm)
+	}
+	// Read all the PCR banks at once.
+	snap, err := p.reader.Snapshot()
 	if err != nil {
-		panic(err)
+		return err
 	}
-	// Read each PCR bank.
-	for _, alg := range algs {
-		pcrBanks = append(pcrBanks, pcrBank(tpm, alg))
+	layout, err := p.reader.Layout()
+	if err != nil {
+		return err
+	}
+	props, err := p.reader.Properties()
+	if err != nil {
+		return err
+	}
+	pcrBanks := make([]pcrData, 0)
+	for _, bank := range snap.Banks {
+		pcrBanks = append(pcrBanks, pcrBank(layout, props, bank))
 	}
 	p.pcrs = pcrBanks
+	p.Block.Title = fmt.Sprintf("PCRs (update counter: %d)", snap.UpdateCounter)
+	return nil
 }
 
-// pcrBank reads all the PCRs in the selected bank.
-func pcrBank(tpm io.ReadWriter, alg tpm2.Algorithm) pcrData {
-	pcrs := make([][]byte, 24)
-	// Read PCRs 8 at a time, the max supported by the TPM.
-	for i := 0; i < 24; i += 8 {
-		sel := tpm2.PCRSelection{
-			Hash: alg,
-			PCRs: []int{i, i + 1, i + 2, i + 3, i + 4, i + 5, i + 6, i + 7},
+// pcrBank lays out the PCR bank by index, with each PCR's annotation. PCRs
+// that are not allocated are left empty.
+func pcrBank(layout pcrs.Layout, props []pcrs.Properties, bank pcrs.Bank) pcrData {
+	hashes := make([][]byte, layout.Count)
+	for idx, hash := range bank.PCRs {
+		if idx < len(hashes) {
+			hashes[idx] = hash
 		}
-		hashes, err := tpm2.ReadPCRs(tpm, sel)
-		if err != nil {
-			panic(err)
-		}
-		for idx, hash := range hashes {
-			pcrs[idx] = hash
+	}
+	notes := make([]string, layout.Count)
+	for idx := range notes {
+		if idx < len(props) {
+			notes[idx] = props[idx].Annotation()
 		}
 	}
 	return pcrData{
-		alg:    alg,
-		hashes: pcrs,
+		alg:    bank.Hash,
+		hashes: hashes,
+		notes:  notes,
 	}
 }
 
@@ -94,20 +121,33 @@ func cellsFromPcr(pcr *pcrData, w int) [][]ui.Cell {
 	title := algName(pcr.alg)
 	result = append(result, ui.RunesToStyledCells(title, pcrAlgStyle))
 
+	// Leave room for the annotations if that doesn't push the data onto more
+	// than two lines.
+	noteWidth := pcr.noteWidth()
+	if noteWidth != 0 {
+		noteWidth++
+		if w-noteWidth < 3+1+digestLength(pcr.hashes) {
+			noteWidth = 0
+		}
+	}
+
 	for i, hash := range pcr.hashes {
 		// For each PCR value, pretty-print the index and the data.
 		// For some algorithms, the PCR text will need to span multiple lines.
 		// In that case, don't print the index again, but leave space.
-		idxWidth, paddingWidth, dataWidth := decidePcrWidths(w, len(hash))
+		idxWidth, paddingWidth, dataWidth := decidePcrWidths(w-noteWidth, len(hash))
 		index := indexString(i, idxWidth)
+		note := noteString(pcr.notes[i], noteWidth)
 		lines := pcrStrings(hash, dataWidth)
 		for _, line := range lines {
 			resultLine := ui.RunesToStyledCells(index, pcrIndexStyle)
 			resultLine = append(resultLine, ui.RunesToStyledCells(make([]rune, paddingWidth), paddingStyle)...)
+			resultLine = append(resultLine, ui.RunesToStyledCells(note, pcrNoteStyle)...)
 			resultLine = append(resultLine, ui.RunesToStyledCells(line, pcrDataStyle)...)
 			result = append(result, resultLine)
-			// Clear the index area for multi-line PCRs
+			// Clear the index and annotation areas for multi-line PCRs
 			index = make([]rune, len(index))
+			note = make([]rune, len(note))
 		}
 	}
 
@@ -122,6 +162,15 @@ func indexString(index, width int) []rune {
 	return []rune(fmt.Sprintf("%02d:", index))
 }
 
+// noteString pads the given annotation to the width, or drops it if the width
+// is 0.
+func noteString(note string, width int) []rune {
+	if width == 0 {
+		return nil
+	}
+	return []rune(fmt.Sprintf("%-*s", width, note))
+}
+
 // pcrStrings formats the given hash for pretty-printing, depending on the width.
 func pcrStrings(hash []byte, width int) [][]rune {
 	result := make([][]rune, 0)
@@ -135,7 +184,8 @@ func pcrStrings(hash []byte, width int) [][]rune {
 // Draw implements the termui Drawable interface.
 func (p *PcrView) Draw(buf *ui.Buffer) {
 	p.Block.Draw(buf)
-	y := p.Block.Inner.Min.Y - 1 // Inner.Min.Y inexplicably leaves an empty line.
+	// y is relative to the inner rectangle.
+	y := 0
 	// For each PCR bank we have data for,
 	for _, pcr := range p.pcrs {
 		// Grab the rectangle of characters we want to print for this PCR bank.
@@ -159,6 +209,30 @@ func (p *PcrView) Draw(buf *ui.Buffer) {
 type pcrData struct {
 	alg    tpm2.Algorithm
 	hashes [][]byte
+	// notes annotate each PCR with its properties, e.g. "reset L4".
+	notes []string
+}
+
+// noteWidth returns the width of the longest annotation.
+func (p *pcrData) noteWidth() int {
+	width := 0
+	for _, note := range p.notes {
+		if len(note) > width {
+			width = len(note)
+		}
+	}
+	return width
+}
+
+// digestLength returns the length of the longest PCR value.
+func digestLength(hashes [][]byte) int {
+	length := 0
+	for _, hash := range hashes {
+		if len(hash) > length {
+			length = len(hash)
+		}
+	}
+	return length
 }
 
 // decidePcrWidths decides the spacing to give to the PCR index, padding, and PCR data.
@@ -196,21 +270,10 @@ func decidePcrWidths(width int, dataLength int) (indexWidth, paddingWidth, dataW
 }
 
 // algName prints out the name of the PCR algorithm if it's known.
-func algName(alg tpm2.Algorithm) []rune {
-	var niceName string
-	switch alg {
-	case tpm2.AlgSHA1:
-		niceName = "SHA1"
-		break
-	case tpm2.AlgSHA256:
-		niceName = "SHA2-256"
-		break
-	case tpm2.AlgSHA384:
-		niceName = "SHA2-384"
-		break
-	default:
-		niceName = "UNKNOWN"
-		break
-	}
-	return []rune(fmt.Sprintf("%s (0x%04x)", niceName, alg))
+func algName(id tpm2.Algorithm) []rune {
+	niceName := "UNKNOWN"
+	if a, ok := alg.ByID(id); ok {
+		niceName = a.String()
+	}
+	return []rune(fmt.Sprintf("%s (0x%04x)", niceName, id))
 }
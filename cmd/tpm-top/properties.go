@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/chrisfenner/tpm-top/pkg/caps"
+	"github.com/chrisfenner/tpm-top/pkg/pt"
+	"github.com/chrisfenner/tpm-top/pkg/vendor"
+	ui "github.com/gizak/termui/v3"
+	"github.com/google/go-tpm/tpm2"
+)
+
+const (
+	// ptFixed and ptVar are the first fixed and variable TPM properties.
+	ptFixed tpm2.TPMProp = 0x100
+	ptVar   tpm2.TPMProp = 0x200
+)
+
+// platformSpecs are the TPM_PS values of TPM_PT_PS_FAMILY_INDICATOR.
+var platformSpecs = map[uint32]string{
+	0x00: "main",
+	0x01: "PC Client",
+	0x02: "PDA",
+	0x03: "Cell Phone",
+	0x04: "Server",
+	0x05: "Peripheral",
+	0x06: "TSS",
+	0x07: "Storage",
+	0x08: "Authentication",
+	0x09: "Embedded",
+	0x0A: "Hardcopy",
+	0x0B: "Infrastructure",
+	0x0C: "Virtualization",
+	0x0D: "TNC",
+	0x0E: "Multi-tenant",
+	0x0F: "Technical Committee",
+}
+
+// PropertiesView is a termui widget that shows the TPM's identity and fixed
+// properties, and its variable properties.
+type PropertiesView struct {
+	ui.Block
+	sections []section
+	// tpm is the TPM connection that fixed was read from.
+	tpm io.ReadWriter
+	// fixed caches the fixed properties, which do not change.
+	fixed map[tpm2.TPMProp]uint32
+}
+
+// NewPropertiesView creates a new PropertiesView.
+func NewPropertiesView() *PropertiesView {
+	result := &PropertiesView{
+		Block: *ui.NewBlock(),
+	}
+	result.Block.Title = "TPM properties"
+	return result
+}
+
+// Name implements View.
+func (p *PropertiesView) Name() string {
+	return "TPM"
+}
+
+// Refresh refreshes the view with new data from the TPM.
+func (p *PropertiesView) Refresh(tpm io.ReadWriter) error {
+	if tpm != p.tpm {
+		fixed, err := caps.Properties(tpm, ptFixed)
+		if err != nil {
+			return err
+		}
+		p.tpm = tpm
+		p.fixed = fixed
+	}
+	variable, err := caps.Properties(tpm, ptVar)
+	if err != nil {
+		return err
+	}
+	p.sections = append(fixedSections(p.fixed), variableSection(variable))
+	return nil
+}
+
+// fixedSections describes the fixed properties.
+func fixedSections(f map[tpm2.TPMProp]uint32) []section {
+	identity := section{title: "Identity"}
+	manufacturer := f[tpm2.Manufacturer]
+	identity.add("Manufacturer", "%s (%q, 0x%08x)", vendor.Name(manufacturer), vendor.String(manufacturer), manufacturer)
+	identity.add("Vendor string", "%q", vendor.Strings(f[tpm2.VendorString1], f[tpm2.VendorString2], f[tpm2.VendorString3], f[tpm2.VendorString4]))
+	identity.add("TPM type", "0x%08x", f[tpm2.VendorTPMType])
+	// The meaning of the firmware version is vendor-specific.
+	identity.add("Firmware", "0x%08x 0x%08x", f[tpm2.FirmwareVersion1], f[tpm2.FirmwareVersion2])
+
+	spec := section{title: "Specification"}
+	spec.add("Family", "%s", vendor.String(f[tpm2.FamilyIndicator]))
+	spec.add("Level", "%d", f[tpm2.SpecLevel])
+	spec.add("Revision", "%s", revision(f[tpm2.SpecRevision]))
+	spec.add("Date", "%s", specDate(f[tpm2.SpecYear], f[tpm2.SpecDayOfYear]))
+	ps, ok := platformSpecs[f[tpm2.PSFamilyIndicator]]
+	if !ok {
+		ps = fmt.Sprintf("0x%x", f[tpm2.PSFamilyIndicator])
+	}
+	spec.add("Platform", "%s, level %d, revision %s", ps, f[tpm2.PSSpecLevel], revision(f[tpm2.PSSpecRevision]))
+	spec.add("Platform date", "%s", specDate(f[tpm2.PSSpecYear], f[tpm2.PSSpecDayOfYear]))
+	spec.add("Modes", "%s", modes(f[tpm2.TPMModes]))
+
+	limits := section{title: "Limits (bytes)"}
+	limits.add("Command", "%d", f[tpm2.CommandMaxSize])
+	limits.add("Response", "%d", f[tpm2.ResponseMaxSize])
+	limits.add("Input buffer", "%d", f[tpm2.InputMaxBufferSize])
+	limits.add("NV buffer", "%d", f[tpm2.NVMaxBufferSize])
+	limits.add("NV index", "%d", f[tpm2.NVIndexMax])
+	limits.add("Digest", "%d", f[tpm2.DigestMaxSize])
+	limits.add("Capability data", "%d", f[tpm2.CapabilityMaxBufferSize])
+	limits.add("Object context", "%d", f[tpm2.ObjectContextMaxSize])
+	limits.add("Session context", "%d", f[tpm2.SessionContextMaxSize])
+
+	resources := section{title: "Resources"}
+	resources.add("Transient objects", "at least %d", f[tpm2.TransientObjectsMin])
+	resources.add("Persistent objects", "at least %d", f[tpm2.PersistentObjectsMin])
+	resources.add("Loaded sessions", "at least %d", f[tpm2.LoadedObjectsMin])
+	resources.add("Active sessions", "at most %d", f[tpm2.ActiveSessionsMax])
+	resources.add("Context gap", "at most %d", f[tpm2.ContextGapMax])
+	resources.add("NV counters", "at most %d", f[tpm2.NVCountersMax])
+	resources.add("PCRs", "%d (select at least %d bytes)", f[tpm2.PCRCount], f[tpm2.PCRSelectMin])
+	resources.add("Commands", "%d (%d library, %d vendor)", f[tpm2.TotalCommands], f[tpm2.LibraryCommands], f[tpm2.VendorCommands])
+	resources.add("Split signing", "at most %d", f[tpm2.SplitSigningMax])
+
+	context := section{title: "Contexts and memory"}
+	context.add("Context HMAC", "%s", alg.Name(tpm2.Algorithm(f[tpm2.ContextHash])))
+	context.add("Context encryption", "%s-%d", alg.Name(tpm2.Algorithm(f[tpm2.ContextSym])), f[tpm2.ContextSymSize])
+	context.add("Memory", "%s", memory(f[tpm2.MemoryMethod]))
+	context.add("Clock update", "every %d ms", f[tpm2.ClockUpdate])
+	context.add("Orderly count", "%d", f[tpm2.OrderlyCount])
+
+	return []section{identity, spec, limits, resources, context}
+}
+
+// variableSection lists the variable properties by name.
+func variableSection(v map[tpm2.TPMProp]uint32) section {
+	s := section{title: "Variable properties"}
+	for _, prop := range pt.Properties() {
+		value, ok := v[prop.Tag]
+		if !ok {
+			continue
+		}
+		name := strings.TrimPrefix(prop.Name, "TPM_PT_")
+		switch prop.Tag {
+		case tpm2.TPMAPermanent, tpm2.TPMAStartupClear, tpm2.AlgorithmSet:
+			s.add(name, "0x%08x", value)
+		default:
+			s.add(name, "%d", value)
+		}
+	}
+	return s
+}
+
+// revision formats a specification revision, which is the revision times
+// 100.
+func revision(value uint32) string {
+	return fmt.Sprintf("%d.%02d", value/100, value%100)
+}
+
+// specDate formats a specification date given by its year and day of year.
+func specDate(year, day uint32) string {
+	if year == 0 {
+		return "unknown"
+	}
+	return time.Date(int(year), time.January, int(day), 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+}
+
+// modes decodes a TPMA_MODES.
+func modes(value uint32) string {
+	if value&1 != 0 {
+		return "FIPS 140-2"
+	}
+	return "none"
+}
+
+// memory decodes a TPMA_MEMORY.
+func memory(value uint32) string {
+	names := []string{"shared RAM", "shared NV", "objects copied to RAM"}
+	result := make([]string, 0)
+	for i, name := range names {
+		if value&(1<<uint(i)) != 0 {
+			result = append(result, name)
+		}
+	}
+	if len(result) == 0 {
+		return "none"
+	}
+	return strings.Join(result, ", ")
+}
+
+// Draw implements the termui Drawable interface.
+func (p *PropertiesView) Draw(buf *ui.Buffer) {
+	p.Block.Draw(buf)
+	drawSections(buf, p.Block.Inner, p.sections)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"image"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+// sectionGap is the number of columns between columns of sections.
+const sectionGap = 3
+
+var (
+	sectionTitleStyle = ui.Style{
+		Fg:       226,
+		Bg:       ui.ColorClear,
+		Modifier: ui.ModifierBold | ui.ModifierUnderline,
+	}
+	labelStyle = ui.Style{
+		Fg:       14,
+		Bg:       ui.ColorClear,
+		Modifier: ui.ModifierBold,
+	}
+	valueStyle = ui.Style{
+		Fg:       15,
+		Bg:       ui.ColorClear,
+		Modifier: ui.ModifierClear,
+	}
+	// dimStyle is for values that are unset or not interesting.
+	dimStyle = ui.Style{
+		Fg:       244,
+		Bg:       ui.ColorClear,
+		Modifier: ui.ModifierClear,
+	}
+	// warnStyle is for values that need attention.
+	warnStyle = ui.Style{
+		Fg:       196,
+		Bg:       ui.ColorClear,
+		Modifier: ui.ModifierBold,
+	}
+)
+
+// row is a labelled value in a section.
+type row struct {
+	label string
+	value string
+	style ui.Style
+}
+
+// section is a titled list of labelled values.
+type section struct {
+	title string
+	rows  []row
+}
+
+// add adds a row with the formatted value.
+func (s *section) add(label, format string, args ...interface{}) {
+	s.addStyled(label, valueStyle, format, args...)
+}
+
+// addStyled adds a row with the formatted value in the given style.
+func (s *section) addStyled(label string, style ui.Style, format string, args ...interface{}) {
+	s.rows = append(s.rows, row{
+		label: label,
+		value: fmt.Sprintf(format, args...),
+		style: style,
+	})
+}
+
+// height returns the number of lines the section takes, with the title.
+func (s *section) height() int {
+	return 1 + len(s.rows)
+}
+
+// labelWidth returns the width of the longest label.
+func (s *section) labelWidth() int {
+	width := 0
+	for _, r := range s.rows {
+		if len(r.label) > width {
+			width = len(r.label)
+		}
+	}
+	return width
+}
+
+// width returns the width of the section.
+func (s *section) width() int {
+	width := len(s.title)
+	labels := s.labelWidth()
+	for _, r := range s.rows {
+		if w := labels + 2 + len([]rune(r.value)); w > width {
+			width = w
+		}
+	}
+	return width
+}
+
+// drawSections draws the sections in columns, filling each column from the
+// top before starting the next. Sections that do not fit are left out.
+func drawSections(buf *ui.Buffer, inner image.Rectangle, sections []section) {
+	// Split the sections into columns, with a blank line between sections.
+	columns := make([][]section, 0)
+	y := 0
+	for _, s := range sections {
+		if len(columns) == 0 || (y != 0 && y+s.height() > inner.Dy()) {
+			columns = append(columns, nil)
+			y = 0
+		}
+		columns[len(columns)-1] = append(columns[len(columns)-1], s)
+		y += s.height() + 1
+	}
+
+	x := inner.Min.X
+	for _, column := range columns {
+		width := 0
+		for _, s := range column {
+			if w := s.width(); w > width {
+				width = w
+			}
+		}
+		if width > inner.Max.X-x {
+			width = inner.Max.X - x
+		}
+		y := inner.Min.Y
+		for _, s := range column {
+			if y >= inner.Max.Y {
+				break
+			}
+			buf.SetString(ui.TrimString(s.title, width), sectionTitleStyle, image.Pt(x, y))
+			y++
+			labels := s.labelWidth()
+			for _, r := range s.rows {
+				if y >= inner.Max.Y {
+					break
+				}
+				label := ui.TrimString(fmt.Sprintf("%-*s  ", labels, r.label), width)
+				buf.SetString(label, labelStyle, image.Pt(x, y))
+				if rest := width - len(label); rest > 0 {
+					buf.SetString(ui.TrimString(r.value, rest), r.style, image.Pt(x+len(label), y))
+				}
+				y++
+			}
+			y++
+		}
+		x += width + sectionGap
+		if x >= inner.Max.X {
+			return
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+// View is one of the tabs of tpm-top.
+type View interface {
+	ui.Drawable
+	// Name returns the name of the view's tab.
+	Name() string
+	// Refresh refreshes the view with new data from the TPM.
+	Refresh(tpm io.ReadWriter) error
+}
+
+// keyHandler is a View with keys of its own, e.g. to scroll.
+type keyHandler interface {
+	// HandleKey handles a key press, and reports whether the view used it
+	// and whether it needs new data from the TPM.
+	HandleKey(key string) (used, refresh bool)
+	// Keys describes the view's keys for the help, one per line.
+	Keys() []string
+}
+
+// views creates the views of tpm-top, in tab order. The first nine can be
+// picked with the number keys.
+func views() []View {
+	return []View{
+		NewPcrView(),
+		NewPropertiesView(),
+		NewHandlesView(),
+		NewNVView(),
+		NewClockView(),
+		NewLockoutView(),
+	}
+}
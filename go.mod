@@ -6,4 +6,5 @@ require (
 	github.com/gizak/termui/v3 v3.1.0
 	github.com/google/go-attestation v0.3.2
 	github.com/google/go-tpm v0.3.2
+	golang.org/x/crypto v0.0.0-20210314154223-e6e6c4f2bb5b
 )
@@ -0,0 +1,73 @@
+package sm3
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// sm3Tests are the examples of GB/T 32905-2016, and a longer input checked
+// against OpenSSL.
+var sm3Tests = []struct {
+	in   []byte
+	want string
+}{
+	{[]byte("abc"), "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+	{[]byte(strings.Repeat("abcd", 16)), "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+	{bytes.Repeat(allBytes(), 3), "9fc8d5a910965de08dbd81fa00771f102d400b071d873d089cbcc35e6f3f9db1"},
+}
+
+// allBytes returns the bytes 0 to 255 in order.
+func allBytes() []byte {
+	result := make([]byte, 256)
+	for i := range result {
+		result[i] = byte(i)
+	}
+	return result
+}
+
+func TestSum(t *testing.T) {
+	for _, test := range sm3Tests {
+		got := Sum(test.in)
+		if hex.EncodeToString(got[:]) != test.want {
+			t.Errorf("Sum(%d bytes) = %x, want %s", len(test.in), got, test.want)
+		}
+	}
+}
+
+func TestSplitWrites(t *testing.T) {
+	// Writes of every size split the input at every offset around the block
+	// boundaries.
+	for _, test := range sm3Tests {
+		for size := 1; size <= 2*BlockSize+1; size++ {
+			d := New()
+			for in := test.in; len(in) != 0; {
+				n := size
+				if n > len(in) {
+					n = len(in)
+				}
+				d.Write(in[:n])
+				in = in[n:]
+			}
+			if got := hex.EncodeToString(d.Sum(nil)); got != test.want {
+				t.Errorf("%d bytes in writes of %d: digest = %s, want %s", len(test.in), size, got, test.want)
+			}
+		}
+	}
+}
+
+func TestSumDoesNotChangeState(t *testing.T) {
+	d := New()
+	d.Write([]byte("ab"))
+	d.Sum(nil)
+	d.Write([]byte("c"))
+	if got, want := hex.EncodeToString(d.Sum(nil)), sm3Tests[0].want; got != want {
+		t.Errorf("digest after Sum and Write = %s, want %s", got, want)
+	}
+	d.Reset()
+	d.Write([]byte("abc"))
+	if got, want := hex.EncodeToString(d.Sum(nil)), sm3Tests[0].want; got != want {
+		t.Errorf("digest after Reset = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,353 @@
+// specgen generates the Go tables in this repository from the structured TPM
+// 2.0 specification data in spec/tpm2.json.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// responseCode is a TPM_RC from TPM 2.0 Part 2.
+type responseCode struct {
+	Format      string `json:"format"`
+	Number      string `json:"number"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// command is a TPM_CC from TPM 2.0 Part 2, with its handle and parameter
+// names from TPM 2.0 Part 3 and its TPMA_CC bits from the reference
+// implementation.
+type command struct {
+	Code           string   `json:"code"`
+	Name           string   `json:"name"`
+	Handles        []string `json:"handles"`
+	Parameters     []string `json:"parameters"`
+	Attributes     []string `json:"attributes"`
+	ResponseHandle string   `json:"responseHandle"`
+}
+
+// GoName is the name of the command's constant: its name without the "TPM2_"
+// prefix and underscores, e.g. PCRAllocate for TPM2_PCR_Allocate.
+func (c command) GoName() string {
+	return strings.ReplaceAll(strings.TrimPrefix(c.Name, "TPM2_"), "_", "")
+}
+
+// commandAttributes are the TPMA_CC bits that may be listed in a command's
+// attributes.
+var commandAttributes = map[string]uint32{
+	"nv":        1 << 22,
+	"extensive": 1 << 23,
+	"flushed":   1 << 24,
+}
+
+// TPMACC computes the TPMA_CC of the command.
+func (c command) TPMACC() (string, error) {
+	n, err := parseNumber(c.Code)
+	if err != nil {
+		return "", err
+	}
+	attrs := uint32(n) & 0xffff
+	if n&(1<<29) != 0 {
+		attrs |= 1 << 29
+	}
+	for _, a := range c.Attributes {
+		bit, ok := commandAttributes[a]
+		if !ok {
+			return "", fmt.Errorf("unknown attribute %q", a)
+		}
+		attrs |= bit
+	}
+	if len(c.Handles) > 7 {
+		return "", fmt.Errorf("too many handles")
+	}
+	attrs |= uint32(len(c.Handles)) << 25
+	if c.ResponseHandle != "" {
+		attrs |= 1 << 28
+	}
+	return fmt.Sprintf("0x%08x", attrs), nil
+}
+
+// property is a TPM_PT from TPM 2.0 Part 2.
+type property struct {
+	Tag         string `json:"tag"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// algorithm is a TPM_ALG_ID from the TCG Algorithm Registry.
+type algorithm struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Aliases    []string `json:"aliases"`
+	DigestSize int      `json:"digestSize"`
+}
+
+// specData is the contents of the specification data file.
+type specData struct {
+	ResponseCodes []responseCode `json:"responseCodes"`
+	Commands      []command      `json:"commands"`
+	Properties    []property     `json:"properties"`
+	Algorithms    []algorithm    `json:"algorithms"`
+}
+
+var templates = map[string]string{
+	"rc": `// Code generated by specgen from {{.Source}}. DO NOT EDIT.
+
+package rc
+
+var (
+{{- range $format, $var := .Formats}}
+	// {{$format}} response codes
+	{{$var}} = map[int]rcDetails{
+	{{- range $.Data.ResponseCodes}}{{if eq .Format $format}}
+		{{.Number}}: {
+			{{quote .Name}},
+			{{quote .Description}},
+		},
+	{{- end}}{{end}}
+	}
+{{end -}}
+)
+`,
+	"cc": `// Code generated by specgen from {{.Source}}. DO NOT EDIT.
+
+package cc
+
+import "github.com/google/go-tpm/tpmutil"
+
+// The command codes, named after the commands without the "TPM2_" prefix and
+// underscores.
+const (
+	{{- range .Data.Commands}}
+	{{.GoName}} tpmutil.Command = {{.Code}}
+	{{- end}}
+)
+
+var (
+	// commands is ordered by command code.
+	commands = []Command{
+	{{- range .Data.Commands}}
+		{ {{- .Code}}, {{quote .Name}}, {{.TPMACC}}, {{strings .Handles}}, {{strings .Parameters}}, {{quote .ResponseHandle -}} },
+	{{- end}}
+	}
+)
+`,
+	"pt": `// Code generated by specgen from {{.Source}}. DO NOT EDIT.
+
+package pt
+
+var (
+	// properties is ordered by property tag.
+	properties = []Property{
+	{{- range .Data.Properties}}
+		{ {{- .Tag}}, {{quote .Name}}, {{quote .Description -}} },
+	{{- end}}
+	}
+)
+`,
+	"alg": `// Code generated by specgen from {{.Source}}. DO NOT EDIT.
+
+package alg
+
+var (
+	// algorithms is ordered by algorithm ID.
+	algorithms = []Algorithm{
+	{{- range .Data.Algorithms}}
+		{ {{- .ID}}, {{quote .Name}}, {{strings .Aliases}}, {{.DigestSize -}} },
+	{{- end}}
+	}
+)
+`,
+}
+
+var funcs = template.FuncMap{
+	"quote": strconv.Quote,
+	"strings": func(strs []string) string {
+		if len(strs) == 0 {
+			return "nil"
+		}
+		var buf bytes.Buffer
+		buf.WriteString("[]string{")
+		for i, s := range strs {
+			if i != 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(strconv.Quote(s))
+		}
+		buf.WriteString("}")
+		return buf.String()
+	},
+}
+
+// parseNumber parses a hex number from the specification data.
+func parseNumber(s string) (uint64, error) {
+	return strconv.ParseUint(s, 0, 32)
+}
+
+// check validates the specification data and sorts it into the order the
+// generated tables expect.
+func check(data *specData) error {
+	names := make(map[string]bool)
+	values := make(map[string]bool)
+	for _, c := range data.ResponseCodes {
+		n, err := parseNumber(c.Number)
+		switch c.Format {
+		case "VER1", "WARN":
+			if err != nil || n > 0x7f {
+				return fmt.Errorf("%s: invalid number %q", c.Name, c.Number)
+			}
+		case "FMT1":
+			if err != nil || n > 0x3f {
+				return fmt.Errorf("%s: invalid number %q", c.Name, c.Number)
+			}
+		default:
+			return fmt.Errorf("%s: invalid format %q", c.Name, c.Format)
+		}
+		value := fmt.Sprintf("%s 0x%x", c.Format, n)
+		if names[c.Name] || values[value] {
+			return fmt.Errorf("duplicate response code %s (%s)", c.Name, value)
+		}
+		names[c.Name] = true
+		values[value] = true
+	}
+	goNames := make(map[string]bool)
+	for _, c := range data.Commands {
+		n, err := parseNumber(c.Code)
+		if err != nil {
+			return fmt.Errorf("%s: invalid code %q", c.Name, c.Code)
+		}
+		value := fmt.Sprintf("TPM_CC 0x%x", n)
+		if names[c.Name] || values[value] {
+			return fmt.Errorf("duplicate command %s (%s)", c.Name, value)
+		}
+		if _, err := c.TPMACC(); err != nil {
+			return fmt.Errorf("%s: %w", c.Name, err)
+		}
+		if goNames[c.GoName()] {
+			return fmt.Errorf("%s: duplicate Go name %s", c.Name, c.GoName())
+		}
+		goNames[c.GoName()] = true
+		names[c.Name] = true
+		values[value] = true
+	}
+	for _, p := range data.Properties {
+		n, err := parseNumber(p.Tag)
+		if err != nil {
+			return fmt.Errorf("%s: invalid tag %q", p.Name, p.Tag)
+		}
+		value := fmt.Sprintf("TPM_PT 0x%x", n)
+		if names[p.Name] || values[value] {
+			return fmt.Errorf("duplicate property %s (%s)", p.Name, value)
+		}
+		names[p.Name] = true
+		values[value] = true
+	}
+	aliases := make(map[string]bool)
+	for _, a := range data.Algorithms {
+		n, err := parseNumber(a.ID)
+		if err != nil || n > 0xffff {
+			return fmt.Errorf("%s: invalid ID %q", a.Name, a.ID)
+		}
+		value := fmt.Sprintf("TPM_ALG_ID 0x%x", n)
+		if names[a.Name] || values[value] {
+			return fmt.Errorf("duplicate algorithm %s (%s)", a.Name, value)
+		}
+		names[a.Name] = true
+		values[value] = true
+		if len(a.Aliases) == 0 {
+			return fmt.Errorf("%s: no aliases", a.Name)
+		}
+		for _, alias := range a.Aliases {
+			if aliases[alias] || alias != strings.ToLower(alias) {
+				return fmt.Errorf("%s: invalid or duplicate alias %q", a.Name, alias)
+			}
+			aliases[alias] = true
+		}
+	}
+	sort.SliceStable(data.Commands, func(i, j int) bool {
+		a, _ := parseNumber(data.Commands[i].Code)
+		b, _ := parseNumber(data.Commands[j].Code)
+		return a < b
+	})
+	sort.SliceStable(data.Properties, func(i, j int) bool {
+		a, _ := parseNumber(data.Properties[i].Tag)
+		b, _ := parseNumber(data.Properties[j].Tag)
+		return a < b
+	})
+	sort.SliceStable(data.Algorithms, func(i, j int) bool {
+		a, _ := parseNumber(data.Algorithms[i].ID)
+		b, _ := parseNumber(data.Algorithms[j].ID)
+		return a < b
+	})
+	return nil
+}
+
+func generate(in, table, out string) error {
+	text, ok := templates[table]
+	if !ok {
+		return fmt.Errorf("unknown table %q", table)
+	}
+	raw, err := ioutil.ReadFile(in)
+	if err != nil {
+		return err
+	}
+	var data specData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("could not parse %s: %w", in, err)
+	}
+	if err := check(&data); err != nil {
+		return fmt.Errorf("invalid data in %s: %w", in, err)
+	}
+	tmpl, err := template.New(table).Funcs(funcs).Parse(text)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, struct {
+		Source  string
+		Data    specData
+		Formats map[string]string
+	}{
+		Source: filepath.ToSlash(filepath.Join("spec", filepath.Base(in))),
+		Data:   data,
+		Formats: map[string]string{
+			"VER1": "ver1RespCodes",
+			"FMT1": "fmt1RespCodes",
+			"WARN": "warningRespCodes",
+		},
+	})
+	if err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not format generated code: %w", err)
+	}
+	return ioutil.WriteFile(out, src, 0644)
+}
+
+func main() {
+	in := flag.String("in", "", "path to the specification data file")
+	table := flag.String("table", "", "which table to generate (rc, cc, pt or alg)")
+	out := flag.String("out", "", "path to the generated Go file")
+	flag.Parse()
+	if *in == "" || *table == "" || *out == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := generate(*in, *table, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "specgen: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,143 @@
+// Package alg describes the TPM 2.0 algorithm IDs (TPM_ALG_ID), and provides
+// Go implementations of the hash algorithms.
+package alg
+
+//go:generate go run github.com/chrisfenner/tpm-top/internal/specgen -in ../../spec/tpm2.json -table alg -out tables_gen.go
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/chrisfenner/tpm-top/internal/sm3"
+	"github.com/google/go-tpm/tpm2"
+	"golang.org/x/crypto/sha3"
+)
+
+// Algorithm describes an algorithm from the TCG Algorithm Registry.
+type Algorithm struct {
+	// ID is the algorithm ID (TPM_ALG_ID).
+	ID tpm2.Algorithm
+	// Name is the name of the algorithm in the TPM specification.
+	Name string
+	// Aliases are the lower-case names the algorithm may be given by on the
+	// command line. The first one is the display name.
+	Aliases []string
+	// DigestSize is the size of the algorithm's digests in bytes, or 0 if it
+	// is not a hash algorithm.
+	DigestSize int
+}
+
+// AlgSM3_256 is the algorithm ID of SM3-256, which go-tpm does not define.
+const AlgSM3_256 tpm2.Algorithm = 0x0012
+
+// hashes holds the Go implementations of the hash algorithms.
+var hashes = map[tpm2.Algorithm]func() hash.Hash{
+	tpm2.AlgSHA1:     sha1.New,
+	tpm2.AlgSHA256:   sha256.New,
+	tpm2.AlgSHA384:   sha512.New384,
+	tpm2.AlgSHA512:   sha512.New,
+	AlgSM3_256:       sm3.New,
+	tpm2.AlgSHA3_256: sha3.New256,
+	tpm2.AlgSHA3_384: sha3.New384,
+	tpm2.AlgSHA3_512: sha3.New512,
+}
+
+// String returns the display name of the algorithm, e.g. "SHA2-256".
+func (a Algorithm) String() string {
+	return strings.ToUpper(a.Aliases[0])
+}
+
+// IsHash reports whether the algorithm is a hash algorithm.
+func (a Algorithm) IsHash() bool {
+	return a.DigestSize != 0
+}
+
+// New returns a new Go implementation of the hash algorithm.
+func (a Algorithm) New() (hash.Hash, error) {
+	newHash, ok := hashes[a.ID]
+	if !ok {
+		return nil, fmt.Errorf("no implementation of %v is available", a)
+	}
+	return newHash(), nil
+}
+
+// Digest computes the hash of the data with the hash algorithm.
+func (a Algorithm) Digest(data []byte) ([]byte, error) {
+	h, err := a.New()
+	if err != nil {
+		return nil, err
+	}
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// ByID finds the algorithm with the given algorithm ID.
+func ByID(id tpm2.Algorithm) (Algorithm, bool) {
+	for _, a := range algorithms {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return Algorithm{}, false
+}
+
+// Name returns the display name of the algorithm with the given ID, or its
+// hex value if it is not known.
+func Name(id tpm2.Algorithm) string {
+	if a, ok := ByID(id); ok {
+		return a.String()
+	}
+	return fmt.Sprintf("TPM_ALG(0x%04x)", uint16(id))
+}
+
+// Lookup finds the algorithm with the given name or alias. The match is
+// case-insensitive and the "TPM_ALG_" prefix is optional.
+func Lookup(name string) (Algorithm, bool) {
+	name = strings.ToLower(name)
+	for _, a := range algorithms {
+		if strings.ToLower(a.Name) == name || strings.TrimPrefix(strings.ToLower(a.Name), "tpm_alg_") == name {
+			return a, true
+		}
+		for _, alias := range a.Aliases {
+			if alias == name {
+				return a, true
+			}
+		}
+	}
+	return Algorithm{}, false
+}
+
+// LookupHash is like Lookup, but only finds hash algorithms.
+func LookupHash(name string) (Algorithm, error) {
+	a, ok := Lookup(name)
+	if !ok || !a.IsHash() {
+		names := make([]string, 0)
+		for _, h := range Hashes() {
+			names = append(names, h.Aliases[0])
+		}
+		return Algorithm{}, fmt.Errorf("unrecognized hash algorithm '%s' (expected one of %s)", name, strings.Join(names, ", "))
+	}
+	return a, nil
+}
+
+// Algorithms returns all the known algorithms, ordered by algorithm ID.
+func Algorithms() []Algorithm {
+	result := make([]Algorithm, len(algorithms))
+	copy(result, algorithms)
+	return result
+}
+
+// Hashes returns all the known hash algorithms, ordered by algorithm ID.
+func Hashes() []Algorithm {
+	result := make([]Algorithm, 0)
+	for _, a := range algorithms {
+		if a.IsHash() {
+			result = append(result, a)
+		}
+	}
+	return result
+}
@@ -0,0 +1,27 @@
+package alg
+
+import "testing"
+
+func TestAlgorithmsRoundTrip(t *testing.T) {
+	for _, a := range Algorithms() {
+		if found, ok := ByID(a.ID); !ok || found.Name != a.Name {
+			t.Errorf("ByID(0x%04x) = %s, want %s", a.ID, found.Name, a.Name)
+		}
+		for _, name := range append([]string{a.Name}, a.Aliases...) {
+			if found, ok := Lookup(name); !ok || found.ID != a.ID {
+				t.Errorf("Lookup(%s) = 0x%04x, want 0x%04x", name, found.ID, a.ID)
+			}
+		}
+		if !a.IsHash() {
+			continue
+		}
+		h, err := a.New()
+		if err != nil {
+			t.Errorf("%s: %v", a.Name, err)
+			continue
+		}
+		if h.Size() != a.DigestSize {
+			t.Errorf("%s implementation has digest size %d, want %d", a.Name, h.Size(), a.DigestSize)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Code generated by specgen from spec/tpm2.json. DO NOT EDIT.
+
+package alg
+
+var (
+	// algorithms is ordered by algorithm ID.
+	algorithms = []Algorithm{
+		{0x0001, "TPM_ALG_RSA", []string{"rsa"}, 0},
+		{0x0003, "TPM_ALG_TDES", []string{"tdes"}, 0},
+		{0x0004, "TPM_ALG_SHA1", []string{"sha1", "sha"}, 20},
+		{0x0005, "TPM_ALG_HMAC", []string{"hmac"}, 0},
+		{0x0006, "TPM_ALG_AES", []string{"aes"}, 0},
+		{0x0007, "TPM_ALG_MGF1", []string{"mgf1"}, 0},
+		{0x0008, "TPM_ALG_KEYEDHASH", []string{"keyedhash"}, 0},
+		{0x000A, "TPM_ALG_XOR", []string{"xor"}, 0},
+		{0x000B, "TPM_ALG_SHA256", []string{"sha2-256", "sha256"}, 32},
+		{0x000C, "TPM_ALG_SHA384", []string{"sha2-384", "sha384"}, 48},
+		{0x000D, "TPM_ALG_SHA512", []string{"sha2-512", "sha512"}, 64},
+		{0x0010, "TPM_ALG_NULL", []string{"null"}, 0},
+		{0x0012, "TPM_ALG_SM3_256", []string{"sm3-256", "sm3_256", "sm3"}, 32},
+		{0x0013, "TPM_ALG_SM4", []string{"sm4"}, 0},
+		{0x0014, "TPM_ALG_RSASSA", []string{"rsassa"}, 0},
+		{0x0015, "TPM_ALG_RSAES", []string{"rsaes"}, 0},
+		{0x0016, "TPM_ALG_RSAPSS", []string{"rsapss"}, 0},
+		{0x0017, "TPM_ALG_OAEP", []string{"oaep"}, 0},
+		{0x0018, "TPM_ALG_ECDSA", []string{"ecdsa"}, 0},
+		{0x0019, "TPM_ALG_ECDH", []string{"ecdh"}, 0},
+		{0x001A, "TPM_ALG_ECDAA", []string{"ecdaa"}, 0},
+		{0x001B, "TPM_ALG_SM2", []string{"sm2"}, 0},
+		{0x001C, "TPM_ALG_ECSCHNORR", []string{"ecschnorr"}, 0},
+		{0x001D, "TPM_ALG_ECMQV", []string{"ecmqv"}, 0},
+		{0x0020, "TPM_ALG_KDF1_SP800_56A", []string{"kdf1-sp800-56a"}, 0},
+		{0x0021, "TPM_ALG_KDF2", []string{"kdf2"}, 0},
+		{0x0022, "TPM_ALG_KDF1_SP800_108", []string{"kdf1-sp800-108"}, 0},
+		{0x0023, "TPM_ALG_ECC", []string{"ecc"}, 0},
+		{0x0025, "TPM_ALG_SYMCIPHER", []string{"symcipher"}, 0},
+		{0x0026, "TPM_ALG_CAMELLIA", []string{"camellia"}, 0},
+		{0x0027, "TPM_ALG_SHA3_256", []string{"sha3-256", "sha3_256"}, 32},
+		{0x0028, "TPM_ALG_SHA3_384", []string{"sha3-384", "sha3_384"}, 48},
+		{0x0029, "TPM_ALG_SHA3_512", []string{"sha3-512", "sha3_512"}, 64},
+		{0x003F, "TPM_ALG_CMAC", []string{"cmac"}, 0},
+		{0x0040, "TPM_ALG_CTR", []string{"ctr"}, 0},
+		{0x0041, "TPM_ALG_OFB", []string{"ofb"}, 0},
+		{0x0042, "TPM_ALG_CBC", []string{"cbc"}, 0},
+		{0x0043, "TPM_ALG_CFB", []string{"cfb"}, 0},
+		{0x0044, "TPM_ALG_ECB", []string{"ecb"}, 0},
+	}
+)
@@ -0,0 +1,88 @@
+// Package auth authorizes TPM commands with password, HMAC and policy
+// sessions, which may be salted, bound and encrypt parameters, and reads
+// authorization values from the command line.
+package auth
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// ParseValue parses an authorization value. A "hex:" prefix gives the value
+// in hex, and a "str:" prefix, or no prefix, gives it as a string.
+func ParseValue(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "hex:") {
+		value, err := hex.DecodeString(strings.TrimPrefix(s, "hex:"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex authorization value: %w", err)
+		}
+		return value, nil
+	}
+	return []byte(strings.TrimPrefix(s, "str:")), nil
+}
+
+// Session authorizes the use of a handle in a command.
+type Session interface {
+	// Handle returns the session handle, or TPM_RS_PW for a password.
+	Handle() tpmutil.Handle
+	// Close flushes the session from the TPM.
+	Close(tpm io.ReadWriter) error
+
+	// prepare readies the session for the command that uses it for the
+	// handle, encrypting the first command parameter if the session does
+	// parameter encryption.
+	prepare(c *call, h *Handle) error
+	// command returns the session's TPMS_AUTH_COMMAND for the command.
+	command(c *call, h *Handle) (tpm2.AuthCommand, error)
+	// response checks the session's TPMS_AUTH_RESPONSE for the command.
+	response(c *call, h *Handle, r *authResponse) error
+	// finish decrypts the first response parameter if the session encrypted
+	// it.
+	finish(c *call, h *Handle) error
+}
+
+// password is a password session (TPM_RS_PW).
+type password struct {
+	value []byte
+}
+
+// Password returns a session that sends the authorization value in the clear.
+func Password(value []byte) Session {
+	return &password{value: value}
+}
+
+func (p *password) Handle() tpmutil.Handle {
+	return tpm2.HandlePasswordSession
+}
+
+func (p *password) Close(tpm io.ReadWriter) error {
+	return nil
+}
+
+func (p *password) prepare(c *call, h *Handle) error {
+	return nil
+}
+
+func (p *password) command(c *call, h *Handle) (tpm2.AuthCommand, error) {
+	return tpm2.AuthCommand{
+		Session:    tpm2.HandlePasswordSession,
+		Attributes: tpm2.AttrContinueSession,
+		Auth:       p.value,
+	}, nil
+}
+
+func (p *password) response(c *call, h *Handle, r *authResponse) error {
+	if len(r.Nonce) != 0 || len(r.HMAC) != 0 {
+		return fmt.Errorf("TPM returned a nonce or HMAC for a password session")
+	}
+	return nil
+}
+
+func (p *password) finish(c *call, h *Handle) error {
+	return nil
+}
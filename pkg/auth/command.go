@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/chrisfenner/tpm-top/pkg/cc"
+	"github.com/chrisfenner/tpm-top/pkg/rc"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// Handle is a handle in a command.
+type Handle struct {
+	// Handle is the handle itself.
+	Handle tpmutil.Handle
+	// Name is the name of the entity, which is the handle itself for
+	// hierarchies, PCRs and sessions. If it is nil, the handle is used.
+	// NV indices and objects must set it.
+	Name []byte
+	// Session authorizes the use of the handle, or is nil if the command does
+	// not require authorization for it.
+	Session Session
+	// NewAuth is the authorization value of the handle after the command,
+	// if the command changes it.
+	NewAuth []byte
+}
+
+// name returns the name of the handle.
+func (h *Handle) name() []byte {
+	if h.Name != nil {
+		return h.Name
+	}
+	name, _ := tpmutil.Pack(h.Handle)
+	return name
+}
+
+// authResponse is a TPMS_AUTH_RESPONSE.
+type authResponse struct {
+	Nonce      tpmutil.U16Bytes
+	Attributes tpm2.SessionAttributes
+	HMAC       tpmutil.U16Bytes
+}
+
+// call holds what sessions need to know about a command and its response.
+type call struct {
+	code       tpmutil.Command
+	names      [][]byte
+	parameters []byte
+	// response holds the response parameters.
+	response []byte
+	// decryptCommand is set if the first command parameter is a sized
+	// buffer, which a session may encrypt.
+	decryptCommand bool
+	// encryptResponse is set if the first response parameter is a sized
+	// buffer, which a session may encrypt.
+	encryptResponse bool
+}
+
+// encryptedResponses are the commands whose first response parameter is a
+// sized buffer with a secret worth encrypting.
+var encryptedResponses = map[tpmutil.Command]bool{
+	tpm2.CmdCreate:    true,
+	tpm2.CmdGetRandom: true,
+	tpm2.CmdReadNV:    true,
+	tpm2.CmdUnseal:    true,
+}
+
+// sized returns the contents of the sized buffer at the start of data.
+func sized(data []byte) []byte {
+	if len(data) < 2 {
+		return nil
+	}
+	size := int(data[0])<<8 | int(data[1])
+	if size > len(data)-2 {
+		return nil
+	}
+	return data[2 : 2+size]
+}
+
+// firstParameter returns the contents of the first command parameter, which
+// must be a sized buffer.
+func (c *call) firstParameter() []byte {
+	return sized(c.parameters)
+}
+
+// firstResponseParameter returns the contents of the first response
+// parameter, which must be a sized buffer.
+func (c *call) firstResponseParameter() []byte {
+	return sized(c.response)
+}
+
+// cpHash computes H(commandCode || names || parameters).
+func (c *call) cpHash(a alg.Algorithm) ([]byte, error) {
+	var data bytes.Buffer
+	code, err := tpmutil.Pack(c.code)
+	if err != nil {
+		return nil, err
+	}
+	data.Write(code)
+	for _, name := range c.names {
+		data.Write(name)
+	}
+	data.Write(c.parameters)
+	return a.Digest(data.Bytes())
+}
+
+// rpHash computes H(responseCode || commandCode || parameters) for a
+// successful response.
+func (c *call) rpHash(a alg.Algorithm) ([]byte, error) {
+	var data bytes.Buffer
+	codes, err := tpmutil.Pack(uint32(tpmutil.RCSuccess), c.code)
+	if err != nil {
+		return nil, err
+	}
+	data.Write(codes)
+	data.Write(c.response)
+	return a.Digest(data.Bytes())
+}
+
+// Run runs a command, authorizing each handle that has a session, and
+// returns the response parameters. The parameters are marshalled with
+// tpmutil.Pack. Sessions that do parameter encryption encrypt the first
+// parameter if it is a tpmutil.U16Bytes, and the first response parameter of
+// the commands that return a secret.
+func Run(tpm io.ReadWriter, code tpmutil.Command, handles []Handle, params ...interface{}) ([]byte, error) {
+	parameters, err := tpmutil.Pack(params...)
+	if err != nil {
+		return nil, err
+	}
+	c := call{
+		code:            code,
+		names:           make([][]byte, 0, len(handles)),
+		parameters:      parameters,
+		encryptResponse: encryptedResponses[code],
+	}
+	if len(params) != 0 {
+		_, c.decryptCommand = params[0].(tpmutil.U16Bytes)
+	}
+	var handleArea bytes.Buffer
+	sessions := make([]*Handle, 0)
+	for i := range handles {
+		h := &handles[i]
+		packed, err := tpmutil.Pack(h.Handle)
+		if err != nil {
+			return nil, err
+		}
+		handleArea.Write(packed)
+		c.names = append(c.names, h.name())
+		if h.Session != nil {
+			sessions = append(sessions, h)
+		}
+	}
+	if len(sessions) == 0 {
+		return nil, fmt.Errorf("%s needs at least one session", cc.Name(code))
+	}
+	for _, h := range sessions {
+		if err := h.Session.prepare(&c, h); err != nil {
+			return nil, err
+		}
+	}
+	var authArea bytes.Buffer
+	for _, h := range sessions {
+		auth, err := h.Session.command(&c, h)
+		if err != nil {
+			return nil, err
+		}
+		packed, err := tpmutil.Pack(auth)
+		if err != nil {
+			return nil, err
+		}
+		authArea.Write(packed)
+	}
+	authSize, err := tpmutil.Pack(uint32(authArea.Len()))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, rcode, err := tpmutil.RunCommand(tpm, tpm2.TagSessions, code, tpmutil.RawBytes(handleArea.Bytes()), tpmutil.RawBytes(authSize), tpmutil.RawBytes(authArea.Bytes()), tpmutil.RawBytes(c.parameters))
+	if err != nil {
+		return nil, err
+	}
+	if rcode != tpmutil.RCSuccess {
+		return nil, rc.MakeCommandError(int(rcode), code)
+	}
+
+	// A response with sessions has the response handle, if any, then the
+	// size of the parameters, the parameters, and a TPMS_AUTH_RESPONSE for
+	// each session.
+	buf := bytes.NewBuffer(resp)
+	if command, ok := cc.ByCode(code); ok && command.ResponseHandle != "" {
+		var handle tpmutil.Handle
+		if err := tpmutil.UnpackBuf(buf, &handle); err != nil {
+			return nil, fmt.Errorf("could not decode %s response handle: %w", cc.Name(code), err)
+		}
+	}
+	var parameterSize uint32
+	if err := tpmutil.UnpackBuf(buf, &parameterSize); err != nil {
+		return nil, fmt.Errorf("could not decode %s response: %w", cc.Name(code), err)
+	}
+	c.response = buf.Next(int(parameterSize))
+	if len(c.response) != int(parameterSize) {
+		return nil, fmt.Errorf("could not decode %s response: parameterSize is %d, but only %d bytes follow", cc.Name(code), parameterSize, len(c.response))
+	}
+	for _, h := range sessions {
+		var r authResponse
+		if err := tpmutil.UnpackBuf(buf, &r.Nonce, &r.Attributes, &r.HMAC); err != nil {
+			return nil, fmt.Errorf("could not decode %s response auth area: %w", cc.Name(code), err)
+		}
+		if err := h.Session.response(&c, h, &r); err != nil {
+			return nil, err
+		}
+	}
+	if buf.Len() != 0 {
+		return nil, fmt.Errorf("%d unexpected bytes after the %s response auth area", buf.Len(), cc.Name(code))
+	}
+	for _, h := range sessions {
+		if err := h.Session.finish(&c, h); err != nil {
+			return nil, err
+		}
+	}
+	return c.response, nil
+}
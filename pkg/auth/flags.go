@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/google/go-tpm/tpmutil"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// ValueFlags are the command-line flags that give an authorization value.
+type ValueFlags struct {
+	what   string
+	value  *string
+	file   *string
+	env    *string
+	prompt *bool
+}
+
+// AddValueFlags adds the flags --<prefix>auth, --<prefix>auth-file,
+// --<prefix>auth-env and --<prefix>auth-prompt, which give the authorization
+// value of what.
+func AddValueFlags(fs *flag.FlagSet, prefix, what string) *ValueFlags {
+	return &ValueFlags{
+		what:   what,
+		value:  fs.String(prefix+"auth", "", fmt.Sprintf("the %s authorization value, with an optional hex: or str: prefix", what)),
+		file:   fs.String(prefix+"auth-file", "", fmt.Sprintf("read the %s authorization value from a file", what)),
+		env:    fs.String(prefix+"auth-env", "", fmt.Sprintf("read the %s authorization value from an environment variable", what)),
+		prompt: fs.Bool(prefix+"auth-prompt", false, fmt.Sprintf("prompt for the %s authorization value", what)),
+	}
+}
+
+// Value returns the authorization value given by the flags, or an empty one
+// if none of them were given.
+func (f *ValueFlags) Value() ([]byte, error) {
+	given := 0
+	for _, set := range []bool{*f.value != "", *f.file != "", *f.env != "", *f.prompt} {
+		if set {
+			given++
+		}
+	}
+	if given > 1 {
+		return nil, fmt.Errorf("the %s authorization value is given more than once", f.what)
+	}
+	switch {
+	case *f.value != "":
+		return ParseValue(*f.value)
+	case *f.file != "":
+		data, err := ioutil.ReadFile(*f.file)
+		if err != nil {
+			return nil, err
+		}
+		return ParseValue(strings.TrimSuffix(string(data), "\n"))
+	case *f.env != "":
+		value, ok := os.LookupEnv(*f.env)
+		if !ok {
+			return nil, fmt.Errorf("the environment variable %s is not set", *f.env)
+		}
+		return ParseValue(value)
+	case *f.prompt:
+		value, err := prompt(fmt.Sprintf("Enter the %s authorization value: ", f.what))
+		if err != nil {
+			return nil, err
+		}
+		return ParseValue(value)
+	}
+	return []byte{}, nil
+}
+
+// prompt reads a line from the terminal without echoing it, or from stdin if
+// it is not a terminal.
+func prompt(message string) (string, error) {
+	fmt.Fprint(os.Stderr, message)
+	fd := int(os.Stdin.Fd())
+	if terminal.IsTerminal(fd) {
+		line, err := terminal.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(line), err
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+// Flags are the command-line flags that authorize the use of a handle: its
+// authorization value, and the kind of session to prove it with.
+type Flags struct {
+	*ValueFlags
+	session *string
+	hash    *string
+	encrypt *bool
+	saltKey *string
+}
+
+// AddFlags adds the flags of AddValueFlags with no prefix, and --session and
+// --session-hash, which choose the session.
+func AddFlags(fs *flag.FlagSet, what string) *Flags {
+	return &Flags{
+		ValueFlags: AddValueFlags(fs, "", what),
+		session:    fs.String("session", "password", "the session to authorize with: password, hmac, or policy (with TPM2_PolicyAuthValue)"),
+		hash:       fs.String("session-hash", "sha256", "the hash algorithm of an hmac or policy session"),
+		encrypt:    fs.Bool("encrypt", false, "use a salted, bound hmac or policy session that encrypts the first command and response parameters"),
+		saltKey:    fs.String("salt-key", "", "the handle of a loaded key to salt an --encrypt session with (default: the EK)"),
+	}
+}
+
+// options returns the session options given by the flags, for a session that
+// authorizes the handle with the authorization value. The returned function
+// flushes the salt key, if it was loaded for the session.
+func (f *Flags) options(tpm io.ReadWriter, h Handle, value []byte) (*Options, func(), error) {
+	if !*f.encrypt {
+		if *f.saltKey != "" {
+			return nil, nil, fmt.Errorf("--salt-key needs --encrypt")
+		}
+		return nil, func() {}, nil
+	}
+	opts := &Options{
+		Bind:     &h,
+		BindAuth: value,
+		Encrypt:  true,
+	}
+	if *f.saltKey == "" {
+		ek, flush, err := EK(tpm)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts.Salt = ek
+		return opts, flush, nil
+	}
+	key, err := strconv.ParseUint(*f.saltKey, 0, 32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid salt key handle %q: %w", *f.saltKey, err)
+	}
+	opts.Salt = tpmutil.Handle(key)
+	return opts, func() {}, nil
+}
+
+// Session starts the session given by the flags, to authorize the handle.
+// With --encrypt, the session is salted, bound to the handle and encrypts
+// parameters, and a password session becomes an HMAC session. The caller must
+// close it.
+func (f *Flags) Session(tpm io.ReadWriter, h Handle) (Session, error) {
+	value, err := f.Value()
+	if err != nil {
+		return nil, err
+	}
+	a, err := alg.LookupHash(*f.hash)
+	if err != nil {
+		return nil, err
+	}
+	opts, flush, err := f.options(tpm, h, value)
+	if err != nil {
+		return nil, err
+	}
+	defer flush()
+	session := *f.session
+	if *f.encrypt && session == "password" {
+		session = "hmac"
+	}
+	switch session {
+	case "password":
+		return Password(value), nil
+	case "hmac":
+		return NewHMAC(tpm, a.ID, value, opts)
+	case "policy":
+		s, err := NewPolicy(tpm, a.ID, value, opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.PolicyAuthValue(tpm); err != nil {
+			s.Close(tpm)
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("unknown session %q: expected password, hmac or policy", *f.session)
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// hierarchies are the handles of the hierarchies and lockout, which have
+// their own authorization values.
+var hierarchies = []struct {
+	name   string
+	handle tpmutil.Handle
+}{
+	{"owner", tpm2.HandleOwner},
+	{"endorsement", tpm2.HandleEndorsement},
+	{"lockout", tpm2.HandleLockout},
+	{"platform", tpm2.HandlePlatform},
+}
+
+// Hierarchy returns the handle of a hierarchy, or of lockout, by name.
+func Hierarchy(name string) (tpmutil.Handle, error) {
+	names := make([]string, 0, len(hierarchies))
+	for _, h := range hierarchies {
+		if strings.EqualFold(name, h.name) {
+			return h.handle, nil
+		}
+		names = append(names, h.name)
+	}
+	return 0, fmt.Errorf("unknown hierarchy %q: expected one of %s", name, strings.Join(names, ", "))
+}
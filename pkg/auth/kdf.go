@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"encoding/binary"
+	"hash"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+)
+
+// kdfa derives bits of key material from a key, as KDFa in the TPM 2.0
+// specification: HMAC(key, counter || label || 0 || contextU || contextV ||
+// bits) for each counter, truncated.
+func kdfa(a alg.Algorithm, key []byte, label string, contextU, contextV []byte, bits int) []byte {
+	result := make([]byte, 0, (bits+7)/8+a.DigestSize)
+	for counter := uint32(1); len(result) < (bits+7)/8; counter++ {
+		h := hmac.New(newHash(a), key)
+		binary.Write(h, binary.BigEndian, counter)
+		h.Write([]byte(label))
+		h.Write([]byte{0})
+		h.Write(contextU)
+		h.Write(contextV)
+		binary.Write(h, binary.BigEndian, uint32(bits))
+		result = h.Sum(result)
+	}
+	return result[:(bits+7)/8]
+}
+
+// kdfe derives bits of key material from a shared secret z, as KDFe in the
+// TPM 2.0 specification: H(counter || z || label || 0 || partyU || partyV)
+// for each counter, truncated.
+func kdfe(a alg.Algorithm, z []byte, label string, partyU, partyV []byte, bits int) []byte {
+	result := make([]byte, 0, (bits+7)/8+a.DigestSize)
+	for counter := uint32(1); len(result) < (bits+7)/8; counter++ {
+		h := newHash(a)()
+		binary.Write(h, binary.BigEndian, counter)
+		h.Write(z)
+		h.Write([]byte(label))
+		h.Write([]byte{0})
+		h.Write(partyU)
+		h.Write(partyV)
+		result = h.Sum(result)
+	}
+	return result[:(bits+7)/8]
+}
+
+// newHash returns the constructor of a hash algorithm that is known to have
+// an implementation.
+func newHash(a alg.Algorithm) func() hash.Hash {
+	return func() hash.Hash {
+		h, _ := a.New()
+		return h
+	}
+}
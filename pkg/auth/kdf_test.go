@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// hashAlg returns a hash algorithm for a test.
+func hashAlg(t *testing.T, id tpm2.Algorithm) alg.Algorithm {
+	t.Helper()
+	a, ok := alg.ByID(id)
+	if !ok || !a.IsHash() {
+		t.Fatalf("%s is not a known hash algorithm", alg.Name(id))
+	}
+	return a
+}
+
+// The expected values of the KDF tests are from an independent
+// implementation with Python's hmac and hashlib modules. The SHA-1 KDFa and
+// SHA-384 KDFe cases take two iterations and truncate the second.
+func TestKDFa(t *testing.T) {
+	for _, test := range []struct {
+		hash  tpm2.Algorithm
+		key   []byte
+		label string
+		u, v  []byte
+		bits  int
+		want  string
+	}{
+		{tpm2.AlgSHA256, seq(32), "ATH", fill(0x11, 32), fill(0x22, 32), 256, "a8679444f320a6d0a02c03cac153c458f28758eca056594e887fcefdc2d7e95b"},
+		{tpm2.AlgSHA1, seq(20), "CFB", fill(0x11, 20), fill(0x22, 20), 256, "007fdddb1cb4bdde5e6097a0c49a19349701f27d9ff4a16dff1f37264a9237e1"},
+	} {
+		a := hashAlg(t, test.hash)
+		if got := hex.EncodeToString(kdfa(a, test.key, test.label, test.u, test.v, test.bits)); got != test.want {
+			t.Errorf("%v KDFa(%s, %d bits) = %s, want %s", a, test.label, test.bits, got, test.want)
+		}
+	}
+}
+
+func TestKDFe(t *testing.T) {
+	for _, test := range []struct {
+		hash tpm2.Algorithm
+		size int
+		bits int
+		want string
+	}{
+		{tpm2.AlgSHA256, 32, 256, "c9ab85a38db8730562a12b39b63ef3c3172691f5614ade7c43363d7dbad32eb9"},
+		{tpm2.AlgSHA384, 48, 512, "b5914fd6d851f094460b484d64cd56cfc78dd4a37e9a3b6ade5d3f5216a3acf146c2b03b794634233d5f1d756f9306e75e1958c08d2fe84146f3b4ae14e6e5ba"},
+	} {
+		a := hashAlg(t, test.hash)
+		got := kdfe(a, fill(0x33, test.size), "SECRET", fill(0x44, test.size), fill(0x55, test.size), test.bits)
+		if hex.EncodeToString(got) != test.want {
+			t.Errorf("%v KDFe(SECRET, %d bits) = %x, want %s", a, test.bits, got, test.want)
+		}
+	}
+}
+
+// seq returns the bytes 0 to n-1.
+func seq(n int) []byte {
+	result := make([]byte, n)
+	for i := range result {
+		result[i] = byte(i)
+	}
+	return result
+}
+
+// fill returns n copies of b.
+func fill(b byte, n int) []byte {
+	return bytes.Repeat([]byte{b}, n)
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/chrisfenner/tpm-top/pkg/rc"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// ekHandle is the persistent handle of the RSA endorsement key, from the TCG
+// EK Credential Profile.
+const ekHandle tpmutil.Handle = 0x81010001
+
+// ekTemplate is the default RSA 2048 EK template from the TCG EK Credential
+// Profile.
+var ekTemplate = tpm2.Public{
+	Type:    tpm2.AlgRSA,
+	NameAlg: tpm2.AlgSHA256,
+	Attributes: tpm2.FlagFixedTPM | tpm2.FlagFixedParent | tpm2.FlagSensitiveDataOrigin |
+		tpm2.FlagAdminWithPolicy | tpm2.FlagRestricted | tpm2.FlagDecrypt,
+	// PolicySecret(TPM_RH_ENDORSEMENT).
+	AuthPolicy: []byte{
+		0x83, 0x71, 0x97, 0x67, 0x44, 0x84, 0xB3, 0xF8, 0x1A, 0x90, 0xCC, 0x8D,
+		0x46, 0xA5, 0xD7, 0x24, 0xFD, 0x52, 0xD7, 0x6E, 0x06, 0x52, 0x0B, 0x64,
+		0xF2, 0xA1, 0xDA, 0x1B, 0x33, 0x14, 0x69, 0xAA,
+	},
+	RSAParameters: &tpm2.RSAParams{
+		Symmetric: &tpm2.SymScheme{
+			Alg:     tpm2.AlgAES,
+			KeyBits: 128,
+			Mode:    tpm2.AlgCFB,
+		},
+		KeyBits:    2048,
+		ModulusRaw: make([]byte, 256),
+	},
+}
+
+// EK returns the handle of the endorsement key, to salt sessions with. If it
+// is not persisted at the usual handle, it is created from the default
+// template, which needs the empty endorsement authorization value. The
+// returned function flushes it.
+func EK(tpm io.ReadWriter) (tpmutil.Handle, func(), error) {
+	if _, _, _, err := tpm2.ReadPublic(tpm, ekHandle); err == nil {
+		return ekHandle, func() {}, nil
+	}
+	handle, _, err := tpm2.CreatePrimary(tpm, tpm2.HandleEndorsement, tpm2.PCRSelection{}, "", "", ekTemplate)
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not create the EK: %w", rc.WithCommand(err, tpm2.CmdCreatePrimary))
+	}
+	return handle, func() { tpm2.FlushContext(tpm, handle) }, nil
+}
+
+// encryptSalt picks a salt of the digest size of the loaded key's name
+// algorithm, whatever the session's hash algorithm, and encrypts it to the key,
+// as TPM2_StartAuthSession expects.
+func encryptSalt(tpm io.ReadWriter, key tpmutil.Handle) (salt, encrypted []byte, err error) {
+	pub, _, _, err := tpm2.ReadPublic(tpm, key)
+	if err != nil {
+		return nil, nil, rc.WithCommand(err, tpm2.CmdReadPublic)
+	}
+	nameAlg, ok := alg.ByID(pub.NameAlg)
+	if !ok || !nameAlg.IsHash() {
+		return nil, nil, fmt.Errorf("the salt key's name algorithm %s is not a known hash algorithm", alg.Name(pub.NameAlg))
+	}
+	publicKey, err := pub.Key()
+	if err != nil {
+		return nil, nil, err
+	}
+	switch k := publicKey.(type) {
+	case *rsa.PublicKey:
+		salt, err = newNonce(nameAlg)
+		if err != nil {
+			return nil, nil, err
+		}
+		// The label includes its terminating zero.
+		encrypted, err = rsa.EncryptOAEP(newHash(nameAlg)(), rand.Reader, k, salt, []byte("SECRET\x00"))
+		if err != nil {
+			return nil, nil, err
+		}
+		return salt, encrypted, nil
+	case *ecdsa.PublicKey:
+		return eccSalt(k, nameAlg)
+	}
+	return nil, nil, fmt.Errorf("cannot salt a session with a %T", publicKey)
+}
+
+// eccSalt derives a salt from an ephemeral ECDH exchange with the key, and
+// returns the ephemeral public point as the encrypted salt.
+func eccSalt(k *ecdsa.PublicKey, nameAlg alg.Algorithm) (salt, encrypted []byte, err error) {
+	priv, x, y, err := elliptic.GenerateKey(k.Curve, rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	size := (k.Curve.Params().BitSize + 7) / 8
+	zx, _ := k.Curve.ScalarMult(k.X, k.Y, priv)
+	z := pad(zx, size)
+	salt = kdfe(nameAlg, z, "SECRET", pad(x, size), pad(k.X, size), 8*nameAlg.DigestSize)
+	encrypted, err = tpmutil.Pack(tpmutil.U16Bytes(pad(x, size)), tpmutil.U16Bytes(pad(y, size)))
+	if err != nil {
+		return nil, nil, err
+	}
+	return salt, encrypted, nil
+}
+
+// pad encodes a coordinate in the given number of bytes.
+func pad(n *big.Int, size int) []byte {
+	b := n.Bytes()
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
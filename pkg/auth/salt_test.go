@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+func TestECCSalt(t *testing.T) {
+	// The TPM recovers the salt with the private key, from the ephemeral
+	// point, as in TPM2_StartAuthSession.
+	for _, test := range []struct {
+		curve elliptic.Curve
+		hash  tpm2.Algorithm
+	}{
+		{elliptic.P256(), tpm2.AlgSHA256},
+		{elliptic.P256(), tpm2.AlgSHA384},
+		{elliptic.P384(), tpm2.AlgSHA1},
+	} {
+		a := hashAlg(t, test.hash)
+		priv, err := ecdsa.GenerateKey(test.curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		salt, encrypted, err := eccSalt(&priv.PublicKey, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(salt) != a.DigestSize {
+			t.Errorf("%s with %v: salt is %d bytes, want %d", test.curve.Params().Name, a, len(salt), a.DigestSize)
+		}
+		var x, y tpmutil.U16Bytes
+		if _, err := tpmutil.Unpack(encrypted, &x, &y); err != nil {
+			t.Fatal(err)
+		}
+		size := (test.curve.Params().BitSize + 7) / 8
+		zx, _ := test.curve.ScalarMult(new(big.Int).SetBytes(x), new(big.Int).SetBytes(y), priv.D.Bytes())
+		want := kdfe(a, pad(zx, size), "SECRET", x, pad(priv.X, size), 8*a.DigestSize)
+		if !bytes.Equal(salt, want) {
+			t.Errorf("%s with %v: salt = %x, the TPM would compute %x", test.curve.Params().Name, a, salt, want)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/chrisfenner/tpm-top/pkg/cc"
+	"github.com/chrisfenner/tpm-top/pkg/rc"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// cfbKeyBits is the size of the AES key used for parameter encryption.
+const cfbKeyBits = 128
+
+// authMode is how a session proves knowledge of the authorization value.
+type authMode int
+
+const (
+	// authHMAC proves it with an HMAC keyed with the authorization value.
+	authHMAC authMode = iota
+	// authPassword sends it in the clear, for a policy session with
+	// TPM2_PolicyPassword.
+	authPassword
+	// authNone does not use it, for a policy session that does not need it.
+	authNone
+)
+
+// Options make a session salted, bound or encrypting.
+type Options struct {
+	// Salt is a loaded decryption key, such as the EK, to encrypt a random
+	// salt for the session key to, or 0 for no salt.
+	Salt tpmutil.Handle
+	// Bind is the entity to bind the session to, or nil for none.
+	Bind *Handle
+	// BindAuth is the authorization value of Bind.
+	BindAuth []byte
+	// Encrypt enables AES-128 CFB encryption of the first command and
+	// response parameters, when they are sized buffers.
+	Encrypt bool
+}
+
+// session is an HMAC or policy session.
+type session struct {
+	handle tpmutil.Handle
+	hash   alg.Algorithm
+	policy bool
+	mode   authMode
+	// authValue is the authorization value of the handle the session
+	// authorizes.
+	authValue []byte
+	// sessionKey is empty for an unsalted, unbound session.
+	sessionKey []byte
+	// bindName and bindAuth identify the bind entity, if any.
+	bindName []byte
+	bindAuth []byte
+	encrypt  bool
+	// attributes are the session attributes of the current command.
+	attributes tpm2.SessionAttributes
+	// includeAuth is set if the HMACs of the current command and response
+	// include the authorization value.
+	includeAuth bool
+	nonceCaller []byte
+	nonceTPM    []byte
+}
+
+// startSession starts a session, salted and bound as the options say.
+func startSession(tpm io.ReadWriter, sessionType tpm2.SessionType, hashAlg tpm2.Algorithm, authValue []byte, opts *Options) (*session, error) {
+	a, ok := alg.ByID(hashAlg)
+	if !ok || !a.IsHash() {
+		return nil, fmt.Errorf("%s is not a known hash algorithm", alg.Name(hashAlg))
+	}
+	if _, err := a.New(); err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &Options{}
+	}
+	nonceCaller, err := newNonce(a)
+	if err != nil {
+		return nil, err
+	}
+	tpmKey := tpm2.HandleNull
+	var salt, encryptedSalt []byte
+	if opts.Salt != 0 {
+		tpmKey = opts.Salt
+		salt, encryptedSalt, err = encryptSalt(tpm, opts.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("could not salt the session: %w", err)
+		}
+	}
+	bind := tpm2.HandleNull
+	if opts.Bind != nil {
+		bind = opts.Bind.Handle
+	}
+	// The symmetric algorithm for parameter encryption (TPMT_SYM_DEF), which
+	// go-tpm cannot encode.
+	symmetric := []interface{}{tpm2.AlgNull}
+	if opts.Encrypt {
+		symmetric = []interface{}{tpm2.AlgAES, uint16(cfbKeyBits), tpm2.AlgCFB}
+	}
+	in := []interface{}{tpmKey, bind, tpmutil.U16Bytes(nonceCaller), tpmutil.U16Bytes(encryptedSalt), sessionType}
+	in = append(in, symmetric...)
+	in = append(in, hashAlg)
+	resp, code, err := tpmutil.RunCommand(tpm, tpm2.TagNoSessions, tpm2.CmdStartAuthSession, in...)
+	if err != nil {
+		return nil, err
+	}
+	if code != tpmutil.RCSuccess {
+		return nil, rc.MakeCommandError(int(code), tpm2.CmdStartAuthSession)
+	}
+	var handle tpmutil.Handle
+	var nonceTPM tpmutil.U16Bytes
+	if _, err := tpmutil.Unpack(resp, &handle, &nonceTPM); err != nil {
+		return nil, fmt.Errorf("could not decode TPM2_StartAuthSession response: %w", err)
+	}
+	s := &session{
+		handle:      handle,
+		hash:        a,
+		policy:      sessionType == tpm2.SessionPolicy,
+		authValue:   authValue,
+		encrypt:     opts.Encrypt,
+		nonceCaller: nonceCaller,
+		nonceTPM:    nonceTPM,
+	}
+	if opts.Bind != nil {
+		s.bindName = opts.Bind.name()
+		s.bindAuth = trim(opts.BindAuth)
+	}
+	// sessionKey = KDFa(bindAuth || salt, "ATH", nonceTPM, nonceCaller).
+	if opts.Bind != nil || opts.Salt != 0 {
+		key := append(append([]byte{}, s.bindAuth...), salt...)
+		s.sessionKey = kdfa(a, key, "ATH", nonceTPM, nonceCaller, 8*a.DigestSize)
+	}
+	return s, nil
+}
+
+// newNonce returns a random nonce of the hash algorithm's digest size.
+func newNonce(a alg.Algorithm) ([]byte, error) {
+	nonce := make([]byte, a.DigestSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return nonce, nil
+}
+
+// trim removes the trailing zeros of an authorization value, which the TPM
+// ignores.
+func trim(authValue []byte) []byte {
+	return bytes.TrimRight(authValue, "\x00")
+}
+
+// NewHMAC starts an HMAC session with the hash algorithm, which proves
+// knowledge of the authorization value without sending it to the TPM. The
+// options may be nil for an unsalted, unbound session.
+func NewHMAC(tpm io.ReadWriter, hashAlg tpm2.Algorithm, authValue []byte, opts *Options) (Session, error) {
+	return startSession(tpm, tpm2.SessionHMAC, hashAlg, authValue, opts)
+}
+
+func (s *session) Handle() tpmutil.Handle {
+	return s.handle
+}
+
+func (s *session) Close(tpm io.ReadWriter) error {
+	if err := tpm2.FlushContext(tpm, s.handle); err != nil {
+		return rc.WithCommand(err, tpm2.CmdFlushContext)
+	}
+	return nil
+}
+
+// bound reports whether the session is bound to the entity, with the given
+// authorization value. An HMAC session used on its bind entity already
+// proves knowledge of the authorization value through the session key.
+func (s *session) bound(h *Handle, authValue []byte) bool {
+	return s.bindName != nil && bytes.Equal(h.name(), s.bindName) && bytes.Equal(trim(authValue), s.bindAuth)
+}
+
+// hmacKey returns the key of the session's HMACs: the session key, followed
+// by the authorization value if the session uses it.
+func (s *session) hmacKey(authValue []byte) []byte {
+	key := append([]byte{}, s.sessionKey...)
+	if s.includeAuth {
+		key = append(key, trim(authValue)...)
+	}
+	return key
+}
+
+// hmac computes HMAC(key, digest || nonceNewer || nonceOlder || attributes).
+func (s *session) hmac(key, digest, nonceNewer, nonceOlder []byte, attrs tpm2.SessionAttributes) []byte {
+	h := hmac.New(newHash(s.hash), key)
+	h.Write(digest)
+	h.Write(nonceNewer)
+	h.Write(nonceOlder)
+	h.Write([]byte{byte(attrs)})
+	return h.Sum(nil)
+}
+
+// cfb encrypts or decrypts a parameter in place, with the key and IV derived
+// from sessionKey || authValue and the nonces.
+func (s *session) cfb(h *Handle, authValue []byte, data, nonceNewer, nonceOlder []byte, decrypt bool) error {
+	key := append(append([]byte{}, s.sessionKey...), trim(authValue)...)
+	material := kdfa(s.hash, key, "CFB", nonceNewer, nonceOlder, cfbKeyBits+8*aes.BlockSize)
+	block, err := aes.NewCipher(material[:cfbKeyBits/8])
+	if err != nil {
+		return err
+	}
+	iv := material[cfbKeyBits/8:]
+	if decrypt {
+		cipher.NewCFBDecrypter(block, iv).XORKeyStream(data, data)
+	} else {
+		cipher.NewCFBEncrypter(block, iv).XORKeyStream(data, data)
+	}
+	return nil
+}
+
+func (s *session) prepare(c *call, h *Handle) error {
+	nonce, err := newNonce(s.hash)
+	if err != nil {
+		return err
+	}
+	s.nonceCaller = nonce
+	s.attributes = tpm2.AttrContinueSession
+	// The TPM decides this before running the command, so a response to a
+	// command that changes the authorization value of the bind entity still
+	// leaves it out.
+	s.includeAuth = s.mode == authHMAC && (s.policy || !s.bound(h, s.authValue))
+	if !s.encrypt {
+		return nil
+	}
+	if c.encryptResponse {
+		s.attributes |= tpm2.AttrEcrypt
+	}
+	if c.decryptCommand {
+		s.attributes |= tpm2.AttrDecrypt
+		return s.cfb(h, s.authValue, c.firstParameter(), s.nonceCaller, s.nonceTPM, false)
+	}
+	return nil
+}
+
+func (s *session) command(c *call, h *Handle) (tpm2.AuthCommand, error) {
+	auth := tpm2.AuthCommand{
+		Session:    s.handle,
+		Nonce:      s.nonceCaller,
+		Attributes: s.attributes,
+	}
+	if s.mode == authPassword {
+		auth.Auth = s.authValue
+		return auth, nil
+	}
+	cpHash, err := c.cpHash(s.hash)
+	if err != nil {
+		return tpm2.AuthCommand{}, err
+	}
+	auth.Auth = s.hmac(s.hmacKey(s.authValue), cpHash, s.nonceCaller, s.nonceTPM, auth.Attributes)
+	return auth, nil
+}
+
+func (s *session) response(c *call, h *Handle, r *authResponse) error {
+	s.nonceTPM = r.Nonce
+	// A command that changes the authorization value of the handle
+	// (e.g., TPM2_HierarchyChangeAuth) proves the response with the new
+	// value.
+	if h.NewAuth != nil {
+		s.authValue = h.NewAuth
+	}
+	if s.mode == authPassword {
+		if len(r.HMAC) != 0 {
+			return fmt.Errorf("TPM returned an HMAC for a policy session with TPM2_PolicyPassword")
+		}
+		return nil
+	}
+	rpHash, err := c.rpHash(s.hash)
+	if err != nil {
+		return err
+	}
+	want := s.hmac(s.hmacKey(s.authValue), rpHash, s.nonceTPM, s.nonceCaller, r.Attributes)
+	if !hmac.Equal(want, r.HMAC) {
+		return fmt.Errorf("the response HMAC of session 0x%08x is wrong", uint32(s.handle))
+	}
+	return nil
+}
+
+func (s *session) finish(c *call, h *Handle) error {
+	if s.attributes&tpm2.AttrEcrypt == 0 {
+		return nil
+	}
+	return s.cfb(h, s.authValue, c.firstResponseParameter(), s.nonceTPM, s.nonceCaller, true)
+}
+
+// PolicySession is a policy session. Knowledge of the authorization value is
+// only proven if the policy includes TPM2_PolicyAuthValue or
+// TPM2_PolicyPassword.
+type PolicySession struct {
+	*session
+}
+
+// NewPolicy starts a policy session with the hash algorithm. Run the policy
+// commands on its Handle, or use PolicyAuthValue or PolicyPassword, before
+// using it to authorize a command. The options may be nil for an unsalted,
+// unbound session.
+func NewPolicy(tpm io.ReadWriter, hashAlg tpm2.Algorithm, authValue []byte, opts *Options) (*PolicySession, error) {
+	s, err := startSession(tpm, tpm2.SessionPolicy, hashAlg, authValue, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.mode = authNone
+	return &PolicySession{s}, nil
+}
+
+// PolicyAuthValue runs TPM2_PolicyAuthValue, so that the session proves
+// knowledge of the authorization value with an HMAC.
+func (p *PolicySession) PolicyAuthValue(tpm io.ReadWriter) error {
+	_, code, err := tpmutil.RunCommand(tpm, tpm2.TagNoSessions, cc.PolicyAuthValue, p.handle)
+	if err != nil {
+		return err
+	}
+	if code != tpmutil.RCSuccess {
+		return rc.MakeCommandError(int(code), cc.PolicyAuthValue)
+	}
+	p.mode = authHMAC
+	return nil
+}
+
+// PolicyPassword runs TPM2_PolicyPassword, so that the session sends the
+// authorization value in the clear.
+func (p *PolicySession) PolicyPassword(tpm io.ReadWriter) error {
+	if err := tpm2.PolicyPassword(tpm, p.handle); err != nil {
+		return rc.WithCommand(err, tpm2.CmdPolicyPassword)
+	}
+	p.mode = authPassword
+	return nil
+}
+
+// PolicyDigest returns the session's current policy digest.
+func (p *PolicySession) PolicyDigest(tpm io.ReadWriter) ([]byte, error) {
+	digest, err := tpm2.PolicyGetDigest(tpm, p.handle)
+	if err != nil {
+		return nil, rc.WithCommand(err, tpm2.CmdPolicyGetDigest)
+	}
+	return digest, nil
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+func TestCFB(t *testing.T) {
+	s := &session{hash: hashAlg(t, tpm2.AlgSHA256), sessionKey: seq(32)}
+	plaintext := []byte("parameter encryption test data!!x")
+	nonceNewer, nonceOlder := fill(0x11, 32), fill(0x22, 32)
+	data := append([]byte{}, plaintext...)
+	// Trailing zeros of the authorization value are not part of the key.
+	if err := s.cfb(nil, []byte("pw\x00"), data, nonceNewer, nonceOlder, false); err != nil {
+		t.Fatal(err)
+	}
+	// From openssl enc -aes-128-cfb, with the key and IV from KDFa.
+	want := "51240574a11207d958fe46a0354cf050529149e2efc3dc38a7781780d3311034ae"
+	if got := hex.EncodeToString(data); got != want {
+		t.Errorf("encrypted = %s, want %s", got, want)
+	}
+	if err := s.cfb(nil, []byte("pw"), data, nonceNewer, nonceOlder, true); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, plaintext) {
+		t.Errorf("decrypted = %q, want %q", data, plaintext)
+	}
+}
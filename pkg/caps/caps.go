@@ -0,0 +1,263 @@
+// Package caps reads TPM capabilities (TPM2_GetCapability), including the ones
+// that go-tpm does not decode.
+package caps
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/chrisfenner/tpm-top/pkg/cc"
+	"github.com/chrisfenner/tpm-top/pkg/rc"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// maxCommands is the number of commands requested at a time. It is the most
+// that fit in the reference implementation's 4KB response buffer.
+const maxCommands = 256
+
+// getCapability runs TPM2_GetCapability and returns the capability data,
+// without the capability selector, and whether there is more data.
+func getCapability(tpm io.ReadWriter, capability tpm2.Capability, property, count uint32) ([]byte, bool, error) {
+	resp, code, err := tpmutil.RunCommand(tpm, tpm2.TagNoSessions, tpm2.CmdGetCapability, capability, property, count)
+	if err != nil {
+		return nil, false, err
+	}
+	if code != tpmutil.RCSuccess {
+		return nil, false, rc.MakeCommandError(int(code), tpm2.CmdGetCapability)
+	}
+	var moreData uint8
+	var got tpm2.Capability
+	buf := bytes.NewBuffer(resp)
+	if err := tpmutil.UnpackBuf(buf, &moreData, &got); err != nil {
+		return nil, false, fmt.Errorf("could not decode TPM2_GetCapability response: %w", err)
+	}
+	if got != capability {
+		return nil, false, fmt.Errorf("TPM returned capability %d instead of %d", got, capability)
+	}
+	return buf.Bytes(), moreData != 0, nil
+}
+
+// Commands returns the attributes of all the commands the TPM implements
+// (TPM_CAP_COMMANDS), in command code order.
+func Commands(tpm io.ReadWriter) ([]cc.Attributes, error) {
+	result := make([]cc.Attributes, 0)
+	next := uint32(0)
+	for {
+		data, more, err := getCapability(tpm, tpm2.CapabilityCommands, next, maxCommands)
+		if err != nil {
+			return nil, err
+		}
+		var count uint32
+		buf := bytes.NewBuffer(data)
+		if err := tpmutil.UnpackBuf(buf, &count); err != nil {
+			return nil, fmt.Errorf("could not decode TPML_CCA: %w", err)
+		}
+		for i := uint32(0); i < count; i++ {
+			var attrs uint32
+			if err := tpmutil.UnpackBuf(buf, &attrs); err != nil {
+				return nil, fmt.Errorf("could not decode TPML_CCA: %w", err)
+			}
+			result = append(result, cc.Attributes(attrs))
+			next = uint32(cc.Attributes(attrs).Code()) + 1
+		}
+		if !more || count == 0 {
+			return result, nil
+		}
+	}
+}
+
+// Algorithms returns all the algorithms the TPM implements (TPM_CAP_ALGS), in
+// algorithm ID order.
+func Algorithms(tpm io.ReadWriter) ([]tpm2.AlgorithmDescription, error) {
+	result := make([]tpm2.AlgorithmDescription, 0)
+	next := uint32(0)
+	for {
+		caps, more, err := tpm2.GetCapability(tpm, tpm2.CapabilityAlgs, 64, next)
+		if err != nil {
+			return nil, rc.WithCommand(err, tpm2.CmdGetCapability)
+		}
+		for _, c := range caps {
+			alg, ok := c.(tpm2.AlgorithmDescription)
+			if !ok {
+				return nil, fmt.Errorf("TPM returned %T instead of an algorithm", c)
+			}
+			result = append(result, alg)
+			next = uint32(alg.ID) + 1
+		}
+		if !more || len(caps) == 0 {
+			return result, nil
+		}
+	}
+}
+
+// Property reads a single TPM property (TPM_CAP_TPM_PROPERTIES).
+func Property(tpm io.ReadWriter, prop tpm2.TPMProp) (uint32, error) {
+	caps, _, err := tpm2.GetCapability(tpm, tpm2.CapabilityTPMProperties, 1, uint32(prop))
+	if err != nil {
+		return 0, rc.WithCommand(err, tpm2.CmdGetCapability)
+	}
+	if len(caps) == 0 {
+		return 0, fmt.Errorf("TPM did not report property 0x%x", prop)
+	}
+	tagged, ok := caps[0].(tpm2.TaggedProperty)
+	if !ok || tagged.Tag != prop {
+		return 0, fmt.Errorf("TPM did not report property 0x%x", prop)
+	}
+	return tagged.Value, nil
+}
+
+// Properties reads all the TPM properties (TPM_CAP_TPM_PROPERTIES) in the same
+// group as first, such as PT_FIXED (0x100) or PT_VAR (0x200), starting from
+// first.
+func Properties(tpm io.ReadWriter, first tpm2.TPMProp) (map[tpm2.TPMProp]uint32, error) {
+	result := make(map[tpm2.TPMProp]uint32)
+	next := uint32(first)
+	for {
+		caps, more, err := tpm2.GetCapability(tpm, tpm2.CapabilityTPMProperties, 64, next)
+		if err != nil {
+			return nil, rc.WithCommand(err, tpm2.CmdGetCapability)
+		}
+		for _, c := range caps {
+			prop, ok := c.(tpm2.TaggedProperty)
+			if !ok {
+				return nil, fmt.Errorf("TPM returned %T instead of a property", c)
+			}
+			if uint32(prop.Tag)>>8 != uint32(first)>>8 {
+				return result, nil
+			}
+			result[prop.Tag] = prop.Value
+			next = uint32(prop.Tag) + 1
+		}
+		if !more || len(caps) == 0 {
+			return result, nil
+		}
+	}
+}
+
+// Handles reads all the handles of the same type as first (TPM_CAP_HANDLES),
+// starting from first.
+func Handles(tpm io.ReadWriter, first tpm2.TPMProp) ([]tpmutil.Handle, error) {
+	result := make([]tpmutil.Handle, 0)
+	next := uint32(first)
+	for {
+		caps, more, err := tpm2.GetCapability(tpm, tpm2.CapabilityHandles, 64, next)
+		if err != nil {
+			return nil, rc.WithCommand(err, tpm2.CmdGetCapability)
+		}
+		for _, c := range caps {
+			h, ok := c.(tpmutil.Handle)
+			if !ok {
+				return nil, fmt.Errorf("TPM returned %T instead of a handle", c)
+			}
+			if uint32(h)>>24 != uint32(first)>>24 {
+				return result, nil
+			}
+			result = append(result, h)
+			next = uint32(h) + 1
+		}
+		if !more || len(caps) == 0 {
+			return result, nil
+		}
+	}
+}
+
+const (
+	// htLoadedSession and htSavedSession are TPM_HT_LOADED_SESSION and
+	// TPM_HT_SAVED_SESSION, which select the sessions to list.
+	htLoadedSession uint32 = 0x02000000
+	htSavedSession  uint32 = 0x03000000
+)
+
+// Sessions reads the handles of all the sessions that are loaded, or that
+// are saved if saved is set (TPM_CAP_HANDLES). The TPM reports each session
+// with its HMAC or policy session handle, whichever list it is in.
+func Sessions(tpm io.ReadWriter, saved bool) ([]tpmutil.Handle, error) {
+	first := htLoadedSession
+	if saved {
+		first = htSavedSession
+	}
+	result := make([]tpmutil.Handle, 0)
+	next := first
+	for {
+		caps, more, err := tpm2.GetCapability(tpm, tpm2.CapabilityHandles, 64, next)
+		if err != nil {
+			return nil, rc.WithCommand(err, tpm2.CmdGetCapability)
+		}
+		for _, c := range caps {
+			h, ok := c.(tpmutil.Handle)
+			if !ok {
+				return nil, fmt.Errorf("TPM returned %T instead of a handle", c)
+			}
+			result = append(result, h)
+			// Continue in the same list, whatever the type of the handle.
+			next = first | (uint32(h)&0xffffff + 1)
+		}
+		if !more || len(caps) == 0 {
+			return result, nil
+		}
+	}
+}
+
+// capabilityPCRProperties is TPM_CAP_PCR_PROPERTIES, which go-tpm does not
+// decode.
+const capabilityPCRProperties tpm2.Capability = 7
+
+// maxPCRProperties is the number of PCR properties requested at a time, more
+// than the TPM 2.0 specification defines.
+const maxPCRProperties = 32
+
+// PCRProperty lists the PCRs that have a PCR property (TPMS_TAGGED_PCR_SELECT).
+type PCRProperty struct {
+	// Tag is the PCR property (TPM_PT_PCR).
+	Tag uint32
+	// PCRs are the indices of the PCRs with the property, in ascending
+	// order.
+	PCRs []int
+}
+
+// PCRProperties returns all the PCR properties the TPM reports
+// (TPM_CAP_PCR_PROPERTIES), in tag order.
+func PCRProperties(tpm io.ReadWriter) ([]PCRProperty, error) {
+	result := make([]PCRProperty, 0)
+	next := uint32(0)
+	for {
+		data, more, err := getCapability(tpm, capabilityPCRProperties, next, maxPCRProperties)
+		if err != nil {
+			return nil, err
+		}
+		var count uint32
+		buf := bytes.NewBuffer(data)
+		if err := tpmutil.UnpackBuf(buf, &count); err != nil {
+			return nil, fmt.Errorf("could not decode TPML_TAGGED_PCR_PROPERTY: %w", err)
+		}
+		for i := uint32(0); i < count; i++ {
+			var tag uint32
+			var size uint8
+			if err := tpmutil.UnpackBuf(buf, &tag, &size); err != nil {
+				return nil, fmt.Errorf("could not decode TPMS_TAGGED_PCR_SELECT: %w", err)
+			}
+			bitmap := buf.Next(int(size))
+			if len(bitmap) != int(size) {
+				return nil, fmt.Errorf("could not decode TPMS_TAGGED_PCR_SELECT: pcrSelect is truncated")
+			}
+			prop := PCRProperty{
+				Tag:  tag,
+				PCRs: make([]int, 0),
+			}
+			for j, b := range bitmap {
+				for k := 0; k < 8; k++ {
+					if b&(1<<uint(k)) != 0 {
+						prop.PCRs = append(prop.PCRs, 8*j+k)
+					}
+				}
+			}
+			result = append(result, prop)
+			next = tag + 1
+		}
+		if !more || count == 0 {
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Package cc describes the TPM 2.0 command codes (TPM_CC) and their attributes
+// (TPMA_CC).
+package cc
+
+//go:generate go run github.com/chrisfenner/tpm-top/internal/specgen -in ../../spec/tpm2.json -table cc -out tables_gen.go
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// Attributes is a TPMA_CC, as defined in TPM 2.0 Part 2.
+type Attributes uint32
+
+const (
+	attrCommandIndex Attributes = 0xffff
+	attrNV           Attributes = 1 << 22
+	attrExtensive    Attributes = 1 << 23
+	attrFlushed      Attributes = 1 << 24
+	attrCHandles     Attributes = 7 << 25
+	attrRHandle      Attributes = 1 << 28
+	attrV            Attributes = 1 << 29
+)
+
+// CommandIndex returns the command index, which is the command code of a
+// command that is not vendor-specific.
+func (a Attributes) CommandIndex() uint16 {
+	return uint16(a & attrCommandIndex)
+}
+
+// Code returns the command code the attributes describe.
+func (a Attributes) Code() tpmutil.Command {
+	code := tpmutil.Command(a.CommandIndex())
+	if a.Vendor() {
+		code |= tpmutil.Command(attrV)
+	}
+	return code
+}
+
+// NV reports whether the command may write to NV.
+func (a Attributes) NV() bool {
+	return a&attrNV != 0
+}
+
+// Extensive reports whether the command could flush any number of loaded
+// contexts.
+func (a Attributes) Extensive() bool {
+	return a&attrExtensive != 0
+}
+
+// Flushed reports whether the context associated with any transient handle in
+// the command is flushed when the command completes.
+func (a Attributes) Flushed() bool {
+	return a&attrFlushed != 0
+}
+
+// CHandles returns the number of handles in the handle area of the command.
+func (a Attributes) CHandles() int {
+	return int((a & attrCHandles) >> 25)
+}
+
+// RHandle reports whether the response has a handle area.
+func (a Attributes) RHandle() bool {
+	return a&attrRHandle != 0
+}
+
+// Vendor reports whether the command is vendor-specific.
+func (a Attributes) Vendor() bool {
+	return a&attrV != 0
+}
+
+// String lists the set attributes, e.g. "nv, cHandles=1".
+func (a Attributes) String() string {
+	result := make([]string, 0)
+	if a.NV() {
+		result = append(result, "nv")
+	}
+	if a.Extensive() {
+		result = append(result, "extensive")
+	}
+	if a.Flushed() {
+		result = append(result, "flushed")
+	}
+	result = append(result, fmt.Sprintf("cHandles=%d", a.CHandles()))
+	if a.RHandle() {
+		result = append(result, "rHandle")
+	}
+	if a.Vendor() {
+		result = append(result, "V")
+	}
+	return strings.Join(result, ", ")
+}
+
+// Command describes a TPM 2.0 command: its attributes from TPM 2.0 Part 2 and
+// its handle and parameter areas from TPM 2.0 Part 3.
+type Command struct {
+	// Code is the command code (TPM_CC).
+	Code tpmutil.Command
+	// Name is the name of the command in the TPM specification.
+	Name string
+	// Attributes are the command's attributes (TPMA_CC) in the reference
+	// implementation.
+	Attributes Attributes
+	// Handles are the names of the handles in the handle area, in order.
+	// Handles that require authorization are prefixed with '@'.
+	Handles []string
+	// Parameters are the names of the parameters in the parameter area, in order.
+	Parameters []string
+	// ResponseHandle is the name of the handle in the response handle area, or
+	// empty if the response has none.
+	ResponseHandle string
+}
+
+// HandleNames returns the names of the handles in the handle area, without
+// the '@' authorization marker.
+func (c Command) HandleNames() []string {
+	result := make([]string, 0, len(c.Handles))
+	for _, h := range c.Handles {
+		result = append(result, strings.TrimPrefix(h, "@"))
+	}
+	return result
+}
+
+// AuthHandles returns the names of the handles that require authorization, in
+// the order of the sessions that authorize them.
+func (c Command) AuthHandles() []string {
+	result := make([]string, 0)
+	for _, h := range c.Handles {
+		if strings.HasPrefix(h, "@") {
+			result = append(result, strings.TrimPrefix(h, "@"))
+		}
+	}
+	return result
+}
+
+// Sessions returns the number of authorization sessions the command requires.
+// Up to three sessions may be sent in total; the rest may be used for audit or
+// parameter encryption.
+func (c Command) Sessions() int {
+	return len(c.AuthHandles())
+}
+
+// Lookup finds the command with the given name. The match is case-insensitive
+// and the "TPM2_" or "TPM_CC_" prefix is optional.
+func Lookup(name string) (Command, bool) {
+	name = strings.ToUpper(name)
+	name = strings.TrimPrefix(name, "TPM_CC_")
+	name = strings.TrimPrefix(name, "TPM2_")
+	for _, cmd := range commands {
+		if strings.ToUpper(strings.TrimPrefix(cmd.Name, "TPM2_")) == name {
+			return cmd, true
+		}
+	}
+	return Command{}, false
+}
+
+// ByCode finds the command with the given command code.
+func ByCode(code tpmutil.Command) (Command, bool) {
+	for _, cmd := range commands {
+		if cmd.Code == code {
+			return cmd, true
+		}
+	}
+	return Command{}, false
+}
+
+// Name returns the name of the command with the given command code, or its
+// hex value if it is not known.
+func Name(code tpmutil.Command) string {
+	if cmd, ok := ByCode(code); ok {
+		return cmd.Name
+	}
+	return fmt.Sprintf("TPM_CC(0x%x)", uint32(code))
+}
+
+// Commands returns all the known commands, ordered by command code.
+func Commands() []Command {
+	result := make([]Command, len(commands))
+	copy(result, commands)
+	return result
+}
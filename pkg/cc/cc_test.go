@@ -0,0 +1,29 @@
+package cc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommandsRoundTrip(t *testing.T) {
+	for _, cmd := range Commands() {
+		if found, ok := ByCode(cmd.Code); !ok || found.Name != cmd.Name {
+			t.Errorf("ByCode(0x%x) = %s, want %s", cmd.Code, found.Name, cmd.Name)
+		}
+		if found, ok := Lookup(cmd.Name); !ok || found.Code != cmd.Code {
+			t.Errorf("Lookup(%s) = 0x%x, want 0x%x", cmd.Name, found.Code, cmd.Code)
+		}
+		if cmd.Attributes.Code() != cmd.Code {
+			t.Errorf("%s attributes have code 0x%x, want 0x%x", cmd.Name, cmd.Attributes.Code(), cmd.Code)
+		}
+		if cmd.Attributes.CHandles() != len(cmd.Handles) {
+			t.Errorf("%s attributes have %d handles, want %d", cmd.Name, cmd.Attributes.CHandles(), len(cmd.Handles))
+		}
+		if cmd.Attributes.RHandle() != (cmd.ResponseHandle != "") {
+			t.Errorf("%s attributes have rHandle %v, want response handle %q", cmd.Name, cmd.Attributes.RHandle(), cmd.ResponseHandle)
+		}
+		if !strings.HasPrefix(cmd.Name, "TPM2_") {
+			t.Errorf("command 0x%x name %s does not start with TPM2_", cmd.Code, cmd.Name)
+		}
+	}
+}
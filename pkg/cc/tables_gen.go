@@ -0,0 +1,256 @@
+// Code generated by specgen from spec/tpm2.json. DO NOT EDIT.
+
+package cc
+
+import "github.com/google/go-tpm/tpmutil"
+
+// The command codes, named after the commands without the "TPM2_" prefix and
+// underscores.
+const (
+	NVUndefineSpaceSpecial     tpmutil.Command = 0x11F
+	EvictControl               tpmutil.Command = 0x120
+	HierarchyControl           tpmutil.Command = 0x121
+	NVUndefineSpace            tpmutil.Command = 0x122
+	ChangeEPS                  tpmutil.Command = 0x124
+	ChangePPS                  tpmutil.Command = 0x125
+	Clear                      tpmutil.Command = 0x126
+	ClearControl               tpmutil.Command = 0x127
+	ClockSet                   tpmutil.Command = 0x128
+	HierarchyChangeAuth        tpmutil.Command = 0x129
+	NVDefineSpace              tpmutil.Command = 0x12A
+	PCRAllocate                tpmutil.Command = 0x12B
+	PCRSetAuthPolicy           tpmutil.Command = 0x12C
+	PPCommands                 tpmutil.Command = 0x12D
+	SetPrimaryPolicy           tpmutil.Command = 0x12E
+	FieldUpgradeStart          tpmutil.Command = 0x12F
+	ClockRateAdjust            tpmutil.Command = 0x130
+	CreatePrimary              tpmutil.Command = 0x131
+	NVGlobalWriteLock          tpmutil.Command = 0x132
+	GetCommandAuditDigest      tpmutil.Command = 0x133
+	NVIncrement                tpmutil.Command = 0x134
+	NVSetBits                  tpmutil.Command = 0x135
+	NVExtend                   tpmutil.Command = 0x136
+	NVWrite                    tpmutil.Command = 0x137
+	NVWriteLock                tpmutil.Command = 0x138
+	DictionaryAttackLockReset  tpmutil.Command = 0x139
+	DictionaryAttackParameters tpmutil.Command = 0x13A
+	NVChangeAuth               tpmutil.Command = 0x13B
+	PCREvent                   tpmutil.Command = 0x13C
+	PCRReset                   tpmutil.Command = 0x13D
+	SequenceComplete           tpmutil.Command = 0x13E
+	SetAlgorithmSet            tpmutil.Command = 0x13F
+	SetCommandCodeAuditStatus  tpmutil.Command = 0x140
+	FieldUpgradeData           tpmutil.Command = 0x141
+	IncrementalSelfTest        tpmutil.Command = 0x142
+	SelfTest                   tpmutil.Command = 0x143
+	Startup                    tpmutil.Command = 0x144
+	Shutdown                   tpmutil.Command = 0x145
+	StirRandom                 tpmutil.Command = 0x146
+	ActivateCredential         tpmutil.Command = 0x147
+	Certify                    tpmutil.Command = 0x148
+	PolicyNV                   tpmutil.Command = 0x149
+	CertifyCreation            tpmutil.Command = 0x14A
+	Duplicate                  tpmutil.Command = 0x14B
+	GetTime                    tpmutil.Command = 0x14C
+	GetSessionAuditDigest      tpmutil.Command = 0x14D
+	NVRead                     tpmutil.Command = 0x14E
+	NVReadLock                 tpmutil.Command = 0x14F
+	ObjectChangeAuth           tpmutil.Command = 0x150
+	PolicySecret               tpmutil.Command = 0x151
+	Rewrap                     tpmutil.Command = 0x152
+	Create                     tpmutil.Command = 0x153
+	ECDHZGen                   tpmutil.Command = 0x154
+	HMAC                       tpmutil.Command = 0x155
+	Import                     tpmutil.Command = 0x156
+	Load                       tpmutil.Command = 0x157
+	Quote                      tpmutil.Command = 0x158
+	RSADecrypt                 tpmutil.Command = 0x159
+	HMACStart                  tpmutil.Command = 0x15B
+	SequenceUpdate             tpmutil.Command = 0x15C
+	Sign                       tpmutil.Command = 0x15D
+	Unseal                     tpmutil.Command = 0x15E
+	PolicySigned               tpmutil.Command = 0x160
+	ContextLoad                tpmutil.Command = 0x161
+	ContextSave                tpmutil.Command = 0x162
+	ECDHKeyGen                 tpmutil.Command = 0x163
+	EncryptDecrypt             tpmutil.Command = 0x164
+	FlushContext               tpmutil.Command = 0x165
+	LoadExternal               tpmutil.Command = 0x167
+	MakeCredential             tpmutil.Command = 0x168
+	NVReadPublic               tpmutil.Command = 0x169
+	PolicyAuthorize            tpmutil.Command = 0x16A
+	PolicyAuthValue            tpmutil.Command = 0x16B
+	PolicyCommandCode          tpmutil.Command = 0x16C
+	PolicyCounterTimer         tpmutil.Command = 0x16D
+	PolicyCpHash               tpmutil.Command = 0x16E
+	PolicyLocality             tpmutil.Command = 0x16F
+	PolicyNameHash             tpmutil.Command = 0x170
+	PolicyOR                   tpmutil.Command = 0x171
+	PolicyTicket               tpmutil.Command = 0x172
+	ReadPublic                 tpmutil.Command = 0x173
+	RSAEncrypt                 tpmutil.Command = 0x174
+	StartAuthSession           tpmutil.Command = 0x176
+	VerifySignature            tpmutil.Command = 0x177
+	ECCParameters              tpmutil.Command = 0x178
+	FirmwareRead               tpmutil.Command = 0x179
+	GetCapability              tpmutil.Command = 0x17A
+	GetRandom                  tpmutil.Command = 0x17B
+	GetTestResult              tpmutil.Command = 0x17C
+	Hash                       tpmutil.Command = 0x17D
+	PCRRead                    tpmutil.Command = 0x17E
+	PolicyPCR                  tpmutil.Command = 0x17F
+	PolicyRestart              tpmutil.Command = 0x180
+	ReadClock                  tpmutil.Command = 0x181
+	PCRExtend                  tpmutil.Command = 0x182
+	PCRSetAuthValue            tpmutil.Command = 0x183
+	NVCertify                  tpmutil.Command = 0x184
+	EventSequenceComplete      tpmutil.Command = 0x185
+	HashSequenceStart          tpmutil.Command = 0x186
+	PolicyPhysicalPresence     tpmutil.Command = 0x187
+	PolicyDuplicationSelect    tpmutil.Command = 0x188
+	PolicyGetDigest            tpmutil.Command = 0x189
+	TestParms                  tpmutil.Command = 0x18A
+	Commit                     tpmutil.Command = 0x18B
+	PolicyPassword             tpmutil.Command = 0x18C
+	ZGen2Phase                 tpmutil.Command = 0x18D
+	ECEphemeral                tpmutil.Command = 0x18E
+	PolicyNvWritten            tpmutil.Command = 0x18F
+	PolicyTemplate             tpmutil.Command = 0x190
+	CreateLoaded               tpmutil.Command = 0x191
+	PolicyAuthorizeNV          tpmutil.Command = 0x192
+	EncryptDecrypt2            tpmutil.Command = 0x193
+	ACGetCapability            tpmutil.Command = 0x194
+	ACSend                     tpmutil.Command = 0x195
+	PolicyACSendSelect         tpmutil.Command = 0x196
+	CertifyX509                tpmutil.Command = 0x197
+	ACTSetTimeout              tpmutil.Command = 0x198
+	ECCEncrypt                 tpmutil.Command = 0x199
+	ECCDecrypt                 tpmutil.Command = 0x19A
+	VendorTCGTest              tpmutil.Command = 0x20000000
+)
+
+var (
+	// commands is ordered by command code.
+	commands = []Command{
+		{0x11F, "TPM2_NV_UndefineSpaceSpecial", 0x0440011f, []string{"@nvIndex", "@platform"}, nil, ""},
+		{0x120, "TPM2_EvictControl", 0x04400120, []string{"@auth", "objectHandle"}, []string{"persistentHandle"}, ""},
+		{0x121, "TPM2_HierarchyControl", 0x02c00121, []string{"@authHandle"}, []string{"enable", "state"}, ""},
+		{0x122, "TPM2_NV_UndefineSpace", 0x04400122, []string{"@authHandle", "nvIndex"}, nil, ""},
+		{0x124, "TPM2_ChangeEPS", 0x02c00124, []string{"@authHandle"}, nil, ""},
+		{0x125, "TPM2_ChangePPS", 0x02c00125, []string{"@authHandle"}, nil, ""},
+		{0x126, "TPM2_Clear", 0x02c00126, []string{"@authHandle"}, nil, ""},
+		{0x127, "TPM2_ClearControl", 0x02400127, []string{"@auth"}, []string{"disable"}, ""},
+		{0x128, "TPM2_ClockSet", 0x02400128, []string{"@auth"}, []string{"newTime"}, ""},
+		{0x129, "TPM2_HierarchyChangeAuth", 0x02400129, []string{"@authHandle"}, []string{"newAuth"}, ""},
+		{0x12A, "TPM2_NV_DefineSpace", 0x0240012a, []string{"@authHandle"}, []string{"auth", "publicInfo"}, ""},
+		{0x12B, "TPM2_PCR_Allocate", 0x0240012b, []string{"@authHandle"}, []string{"pcrAllocation"}, ""},
+		{0x12C, "TPM2_PCR_SetAuthPolicy", 0x0240012c, []string{"@authHandle"}, []string{"authPolicy", "hashAlg", "pcrNum"}, ""},
+		{0x12D, "TPM2_PP_Commands", 0x0240012d, []string{"@auth"}, []string{"setList", "clearList"}, ""},
+		{0x12E, "TPM2_SetPrimaryPolicy", 0x0240012e, []string{"@authHandle"}, []string{"authPolicy", "hashAlg"}, ""},
+		{0x12F, "TPM2_FieldUpgradeStart", 0x0400012f, []string{"@authorization", "keyHandle"}, []string{"fuDigest", "manifestSignature"}, ""},
+		{0x130, "TPM2_ClockRateAdjust", 0x02000130, []string{"@auth"}, []string{"rateAdjust"}, ""},
+		{0x131, "TPM2_CreatePrimary", 0x12000131, []string{"@primaryHandle"}, []string{"inSensitive", "inPublic", "outsideInfo", "creationPCR"}, "objectHandle"},
+		{0x132, "TPM2_NV_GlobalWriteLock", 0x02400132, []string{"@authHandle"}, nil, ""},
+		{0x133, "TPM2_GetCommandAuditDigest", 0x04400133, []string{"@privacyHandle", "@signHandle"}, []string{"qualifyingData", "inScheme"}, ""},
+		{0x134, "TPM2_NV_Increment", 0x04400134, []string{"@authHandle", "nvIndex"}, nil, ""},
+		{0x135, "TPM2_NV_SetBits", 0x04400135, []string{"@authHandle", "nvIndex"}, []string{"bits"}, ""},
+		{0x136, "TPM2_NV_Extend", 0x04400136, []string{"@authHandle", "nvIndex"}, []string{"data"}, ""},
+		{0x137, "TPM2_NV_Write", 0x04400137, []string{"@authHandle", "nvIndex"}, []string{"data", "offset"}, ""},
+		{0x138, "TPM2_NV_WriteLock", 0x04400138, []string{"@authHandle", "nvIndex"}, nil, ""},
+		{0x139, "TPM2_DictionaryAttackLockReset", 0x02400139, []string{"@lockHandle"}, nil, ""},
+		{0x13A, "TPM2_DictionaryAttackParameters", 0x0240013a, []string{"@lockHandle"}, []string{"newMaxTries", "newRecoveryTime", "lockoutRecovery"}, ""},
+		{0x13B, "TPM2_NV_ChangeAuth", 0x0240013b, []string{"@nvIndex"}, []string{"newAuth"}, ""},
+		{0x13C, "TPM2_PCR_Event", 0x0240013c, []string{"@pcrHandle"}, []string{"eventData"}, ""},
+		{0x13D, "TPM2_PCR_Reset", 0x0240013d, []string{"@pcrHandle"}, nil, ""},
+		{0x13E, "TPM2_SequenceComplete", 0x0300013e, []string{"@sequenceHandle"}, []string{"buffer", "hierarchy"}, ""},
+		{0x13F, "TPM2_SetAlgorithmSet", 0x0240013f, []string{"@authHandle"}, []string{"algorithmSet"}, ""},
+		{0x140, "TPM2_SetCommandCodeAuditStatus", 0x02400140, []string{"@auth"}, []string{"auditAlg", "setList", "clearList"}, ""},
+		{0x141, "TPM2_FieldUpgradeData", 0x00400141, nil, []string{"fuData"}, ""},
+		{0x142, "TPM2_IncrementalSelfTest", 0x00400142, nil, []string{"toTest"}, ""},
+		{0x143, "TPM2_SelfTest", 0x00400143, nil, []string{"fullTest"}, ""},
+		{0x144, "TPM2_Startup", 0x00400144, nil, []string{"startupType"}, ""},
+		{0x145, "TPM2_Shutdown", 0x00400145, nil, []string{"shutdownType"}, ""},
+		{0x146, "TPM2_StirRandom", 0x00400146, nil, []string{"inData"}, ""},
+		{0x147, "TPM2_ActivateCredential", 0x04000147, []string{"@activateHandle", "@keyHandle"}, []string{"credentialBlob", "secret"}, ""},
+		{0x148, "TPM2_Certify", 0x04000148, []string{"@objectHandle", "@signHandle"}, []string{"qualifyingData", "inScheme"}, ""},
+		{0x149, "TPM2_PolicyNV", 0x06000149, []string{"@authHandle", "nvIndex", "policySession"}, []string{"operandB", "offset", "operation"}, ""},
+		{0x14A, "TPM2_CertifyCreation", 0x0400014a, []string{"@signHandle", "objectHandle"}, []string{"qualifyingData", "creationHash", "inScheme", "creationTicket"}, ""},
+		{0x14B, "TPM2_Duplicate", 0x0400014b, []string{"@objectHandle", "newParentHandle"}, []string{"encryptionKeyIn", "symmetricAlg"}, ""},
+		{0x14C, "TPM2_GetTime", 0x0400014c, []string{"@privacyAdminHandle", "@signHandle"}, []string{"qualifyingData", "inScheme"}, ""},
+		{0x14D, "TPM2_GetSessionAuditDigest", 0x0600014d, []string{"@privacyAdminHandle", "@signHandle", "sessionHandle"}, []string{"qualifyingData", "inScheme"}, ""},
+		{0x14E, "TPM2_NV_Read", 0x0400014e, []string{"@authHandle", "nvIndex"}, []string{"size", "offset"}, ""},
+		{0x14F, "TPM2_NV_ReadLock", 0x0440014f, []string{"@authHandle", "nvIndex"}, nil, ""},
+		{0x150, "TPM2_ObjectChangeAuth", 0x04000150, []string{"@objectHandle", "parentHandle"}, []string{"newAuth"}, ""},
+		{0x151, "TPM2_PolicySecret", 0x04000151, []string{"@authHandle", "policySession"}, []string{"nonceTPM", "cpHashA", "policyRef", "expiration"}, ""},
+		{0x152, "TPM2_Rewrap", 0x04000152, []string{"@oldParent", "newParent"}, []string{"inDuplicate", "name", "inSymSeed"}, ""},
+		{0x153, "TPM2_Create", 0x02000153, []string{"@parentHandle"}, []string{"inSensitive", "inPublic", "outsideInfo", "creationPCR"}, ""},
+		{0x154, "TPM2_ECDH_ZGen", 0x02000154, []string{"@keyHandle"}, []string{"inPoint"}, ""},
+		{0x155, "TPM2_HMAC", 0x02000155, []string{"@handle"}, []string{"buffer", "hashAlg"}, ""},
+		{0x156, "TPM2_Import", 0x02000156, []string{"@parentHandle"}, []string{"encryptionKey", "objectPublic", "duplicate", "inSymSeed", "symmetricAlg"}, ""},
+		{0x157, "TPM2_Load", 0x12000157, []string{"@parentHandle"}, []string{"inPrivate", "inPublic"}, "objectHandle"},
+		{0x158, "TPM2_Quote", 0x02000158, []string{"@signHandle"}, []string{"qualifyingData", "inScheme", "PCRselect"}, ""},
+		{0x159, "TPM2_RSA_Decrypt", 0x02000159, []string{"@keyHandle"}, []string{"cipherText", "inScheme", "label"}, ""},
+		{0x15B, "TPM2_HMAC_Start", 0x1200015b, []string{"@handle"}, []string{"auth", "hashAlg"}, "sequenceHandle"},
+		{0x15C, "TPM2_SequenceUpdate", 0x0200015c, []string{"@sequenceHandle"}, []string{"buffer"}, ""},
+		{0x15D, "TPM2_Sign", 0x0200015d, []string{"@keyHandle"}, []string{"digest", "inScheme", "validation"}, ""},
+		{0x15E, "TPM2_Unseal", 0x0200015e, []string{"@itemHandle"}, nil, ""},
+		{0x160, "TPM2_PolicySigned", 0x04000160, []string{"authObject", "policySession"}, []string{"nonceTPM", "cpHashA", "policyRef", "expiration", "auth"}, ""},
+		{0x161, "TPM2_ContextLoad", 0x10000161, nil, []string{"context"}, "loadedHandle"},
+		{0x162, "TPM2_ContextSave", 0x02000162, []string{"saveHandle"}, nil, ""},
+		{0x163, "TPM2_ECDH_KeyGen", 0x02000163, []string{"keyHandle"}, nil, ""},
+		{0x164, "TPM2_EncryptDecrypt", 0x02000164, []string{"@keyHandle"}, []string{"decrypt", "mode", "ivIn", "inData"}, ""},
+		{0x165, "TPM2_FlushContext", 0x00000165, nil, []string{"flushHandle"}, ""},
+		{0x167, "TPM2_LoadExternal", 0x10000167, nil, []string{"inPrivate", "inPublic", "hierarchy"}, "objectHandle"},
+		{0x168, "TPM2_MakeCredential", 0x02000168, []string{"handle"}, []string{"credential", "objectName"}, ""},
+		{0x169, "TPM2_NV_ReadPublic", 0x02000169, []string{"nvIndex"}, nil, ""},
+		{0x16A, "TPM2_PolicyAuthorize", 0x0200016a, []string{"policySession"}, []string{"approvedPolicy", "policyRef", "keySign", "checkTicket"}, ""},
+		{0x16B, "TPM2_PolicyAuthValue", 0x0200016b, []string{"policySession"}, nil, ""},
+		{0x16C, "TPM2_PolicyCommandCode", 0x0200016c, []string{"policySession"}, []string{"code"}, ""},
+		{0x16D, "TPM2_PolicyCounterTimer", 0x0200016d, []string{"policySession"}, []string{"operandB", "offset", "operation"}, ""},
+		{0x16E, "TPM2_PolicyCpHash", 0x0200016e, []string{"policySession"}, []string{"cpHashA"}, ""},
+		{0x16F, "TPM2_PolicyLocality", 0x0200016f, []string{"policySession"}, []string{"locality"}, ""},
+		{0x170, "TPM2_PolicyNameHash", 0x02000170, []string{"policySession"}, []string{"nameHash"}, ""},
+		{0x171, "TPM2_PolicyOR", 0x02000171, []string{"policySession"}, []string{"pHashList"}, ""},
+		{0x172, "TPM2_PolicyTicket", 0x02000172, []string{"policySession"}, []string{"timeout", "cpHashA", "policyRef", "authName", "ticket"}, ""},
+		{0x173, "TPM2_ReadPublic", 0x02000173, []string{"objectHandle"}, nil, ""},
+		{0x174, "TPM2_RSA_Encrypt", 0x02000174, []string{"keyHandle"}, []string{"message", "inScheme", "label"}, ""},
+		{0x176, "TPM2_StartAuthSession", 0x14000176, []string{"tpmKey", "bind"}, []string{"nonceCaller", "encryptedSalt", "sessionType", "symmetric", "authHash"}, "sessionHandle"},
+		{0x177, "TPM2_VerifySignature", 0x02000177, []string{"keyHandle"}, []string{"digest", "signature"}, ""},
+		{0x178, "TPM2_ECC_Parameters", 0x00000178, nil, []string{"curveID"}, ""},
+		{0x179, "TPM2_FirmwareRead", 0x00000179, nil, []string{"sequenceNumber"}, ""},
+		{0x17A, "TPM2_GetCapability", 0x0000017a, nil, []string{"capability", "property", "propertyCount"}, ""},
+		{0x17B, "TPM2_GetRandom", 0x0000017b, nil, []string{"bytesRequested"}, ""},
+		{0x17C, "TPM2_GetTestResult", 0x0000017c, nil, nil, ""},
+		{0x17D, "TPM2_Hash", 0x0000017d, nil, []string{"data", "hashAlg", "hierarchy"}, ""},
+		{0x17E, "TPM2_PCR_Read", 0x0000017e, nil, []string{"pcrSelectionIn"}, ""},
+		{0x17F, "TPM2_PolicyPCR", 0x0200017f, []string{"policySession"}, []string{"pcrDigest", "pcrs"}, ""},
+		{0x180, "TPM2_PolicyRestart", 0x02000180, []string{"sessionHandle"}, nil, ""},
+		{0x181, "TPM2_ReadClock", 0x00000181, nil, nil, ""},
+		{0x182, "TPM2_PCR_Extend", 0x02400182, []string{"@pcrHandle"}, []string{"digests"}, ""},
+		{0x183, "TPM2_PCR_SetAuthValue", 0x02000183, []string{"@pcrHandle"}, []string{"auth"}, ""},
+		{0x184, "TPM2_NV_Certify", 0x06000184, []string{"@signHandle", "@authHandle", "nvIndex"}, []string{"qualifyingData", "inScheme", "size", "offset"}, ""},
+		{0x185, "TPM2_EventSequenceComplete", 0x05400185, []string{"@pcrHandle", "@sequenceHandle"}, []string{"buffer"}, ""},
+		{0x186, "TPM2_HashSequenceStart", 0x10000186, nil, []string{"auth", "hashAlg"}, "sequenceHandle"},
+		{0x187, "TPM2_PolicyPhysicalPresence", 0x02000187, []string{"policySession"}, nil, ""},
+		{0x188, "TPM2_PolicyDuplicationSelect", 0x02000188, []string{"policySession"}, []string{"objectName", "newParentName", "includeObject"}, ""},
+		{0x189, "TPM2_PolicyGetDigest", 0x02000189, []string{"policySession"}, nil, ""},
+		{0x18A, "TPM2_TestParms", 0x0000018a, nil, []string{"parameters"}, ""},
+		{0x18B, "TPM2_Commit", 0x0200018b, []string{"@signHandle"}, []string{"P1", "s2", "y2"}, ""},
+		{0x18C, "TPM2_PolicyPassword", 0x0200018c, []string{"policySession"}, nil, ""},
+		{0x18D, "TPM2_ZGen_2Phase", 0x0200018d, []string{"@keyA"}, []string{"inQsB", "inQeB", "inScheme", "counter"}, ""},
+		{0x18E, "TPM2_EC_Ephemeral", 0x0000018e, nil, []string{"curveID"}, ""},
+		{0x18F, "TPM2_PolicyNvWritten", 0x0200018f, []string{"policySession"}, []string{"writtenSet"}, ""},
+		{0x190, "TPM2_PolicyTemplate", 0x02000190, []string{"policySession"}, []string{"templateHash"}, ""},
+		{0x191, "TPM2_CreateLoaded", 0x12000191, []string{"@parentHandle"}, []string{"inSensitive", "inPublic"}, "objectHandle"},
+		{0x192, "TPM2_PolicyAuthorizeNV", 0x06000192, []string{"@authHandle", "nvIndex", "policySession"}, nil, ""},
+		{0x193, "TPM2_EncryptDecrypt2", 0x02000193, []string{"@keyHandle"}, []string{"inData", "decrypt", "mode", "ivIn"}, ""},
+		{0x194, "TPM2_AC_GetCapability", 0x02000194, []string{"ac"}, []string{"capability", "count"}, ""},
+		{0x195, "TPM2_AC_Send", 0x06000195, []string{"@sendObject", "@authHandle", "ac"}, []string{"acDataIn"}, ""},
+		{0x196, "TPM2_Policy_AC_SendSelect", 0x02000196, []string{"policySession"}, []string{"objectName", "authHandleName", "acName", "includeObject"}, ""},
+		{0x197, "TPM2_CertifyX509", 0x04000197, []string{"@objectHandle", "@signHandle"}, []string{"reserved", "inScheme", "partialCertificate"}, ""},
+		{0x198, "TPM2_ACT_SetTimeout", 0x02000198, []string{"@actHandle"}, []string{"startTimeout"}, ""},
+		{0x199, "TPM2_ECC_Encrypt", 0x02000199, []string{"keyHandle"}, []string{"plainText", "inScheme"}, ""},
+		{0x19A, "TPM2_ECC_Decrypt", 0x0200019a, []string{"@keyHandle"}, []string{"C1", "C2", "C3", "inScheme"}, ""},
+		{0x20000000, "TPM2_Vendor_TCG_Test", 0x20000000, nil, []string{"inputData"}, ""},
+	}
+)
@@ -0,0 +1,49 @@
+// Package clock reads the TPM's time, clock and boot counters
+// (TPM2_ReadClock), which go-tpm only partly decodes.
+package clock
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/chrisfenner/tpm-top/pkg/rc"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// Info is a TPMS_TIME_INFO.
+type Info struct {
+	// Time is the time in milliseconds since the TPM was last powered on
+	// (_TPM_Init).
+	Time uint64
+	// Clock is the time in milliseconds that the TPM has been powered on
+	// since it was made or cleared.
+	Clock uint64
+	// ResetCount is the number of TPM Resets since the TPM was cleared.
+	ResetCount uint32
+	// RestartCount is the number of TPM Restarts and TPM Resumes since the
+	// last TPM Reset.
+	RestartCount uint32
+	// Safe is set if Clock has not been reported with a value that it then
+	// went back below, e.g. after the TPM lost power without an orderly
+	// shutdown.
+	Safe bool
+}
+
+// Read runs TPM2_ReadClock.
+func Read(tpm io.ReadWriter) (*Info, error) {
+	resp, code, err := tpmutil.RunCommand(tpm, tpm2.TagNoSessions, tpm2.CmdReadClock)
+	if err != nil {
+		return nil, err
+	}
+	if code != tpmutil.RCSuccess {
+		return nil, rc.MakeCommandError(int(code), tpm2.CmdReadClock)
+	}
+	var info Info
+	var safe uint8
+	if _, err := tpmutil.Unpack(resp, &info.Time, &info.Clock, &info.ResetCount, &info.RestartCount, &safe); err != nil {
+		return nil, fmt.Errorf("could not decode TPM2_ReadClock response: %w", err)
+	}
+	info.Safe = safe != 0
+	return &info, nil
+}
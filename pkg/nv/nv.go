@@ -0,0 +1,189 @@
+// Package nv describes NV indices and reads their contents.
+package nv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/chrisfenner/tpm-top/pkg/auth"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+var (
+	// ErrNotWritten is returned when reading an index that has not been
+	// written yet.
+	ErrNotWritten = errors.New("the index has not been written")
+	// ErrReadLocked is returned when reading an index that is read-locked.
+	ErrReadLocked = errors.New("the index is read-locked")
+	// ErrPolicyRead is returned when reading an index that can only be read
+	// with a policy session.
+	ErrPolicyRead = errors.New("the index can only be read with a policy")
+)
+
+// Type is the type of an NV index (TPM_NT).
+type Type uint8
+
+const (
+	TypeOrdinary Type = 0x0
+	TypeCounter  Type = 0x1
+	TypeBits     Type = 0x2
+	TypeExtend   Type = 0x4
+	TypePinFail  Type = 0x8
+	TypePinPass  Type = 0x9
+)
+
+// String returns the name of the type, e.g. "counter".
+func (t Type) String() string {
+	switch t {
+	case TypeOrdinary:
+		return "ordinary"
+	case TypeCounter:
+		return "counter"
+	case TypeBits:
+		return "bits"
+	case TypeExtend:
+		return "extend"
+	case TypePinFail:
+		return "pin fail"
+	case TypePinPass:
+		return "pin pass"
+	}
+	return fmt.Sprintf("type 0x%x", uint8(t))
+}
+
+// TypeOf returns the type of an NV index with the given attributes.
+func TypeOf(attrs tpm2.NVAttr) Type {
+	return Type((attrs >> 4) & 0xf)
+}
+
+// attributes are the names of the TPMA_NV bits, other than the type.
+var attributes = []struct {
+	bit  tpm2.NVAttr
+	name string
+}{
+	{1 << 0, "PPWRITE"},
+	{1 << 1, "OWNERWRITE"},
+	{1 << 2, "AUTHWRITE"},
+	{1 << 3, "POLICYWRITE"},
+	{1 << 10, "POLICY_DELETE"},
+	{1 << 11, "WRITELOCKED"},
+	{1 << 12, "WRITEALL"},
+	{1 << 13, "WRITEDEFINE"},
+	{1 << 14, "WRITE_STCLEAR"},
+	{1 << 15, "GLOBALLOCK"},
+	{1 << 16, "PPREAD"},
+	{1 << 17, "OWNERREAD"},
+	{1 << 18, "AUTHREAD"},
+	{1 << 19, "POLICYREAD"},
+	{1 << 25, "NO_DA"},
+	{1 << 26, "ORDERLY"},
+	{1 << 27, "CLEAR_STCLEAR"},
+	{1 << 28, "READLOCKED"},
+	{1 << 29, "WRITTEN"},
+	{1 << 30, "PLATFORMCREATE"},
+	{1 << 31, "READ_STCLEAR"},
+}
+
+// AttributeNames returns the names of the set TPMA_NV attributes, without
+// the TPMA_NV_ prefix and the type.
+func AttributeNames(attrs tpm2.NVAttr) []string {
+	result := make([]string, 0)
+	for _, a := range attributes {
+		if attrs&a.bit != 0 {
+			result = append(result, a.name)
+		}
+	}
+	return result
+}
+
+// readAuth picks the entity to authorize reading the index with: the
+// platform, the owner, or the index itself. Hierarchies come first, since a
+// failed authorization of the index may count towards dictionary attack
+// lockout.
+func readAuth(pub tpm2.NVPublic) (tpmutil.Handle, error) {
+	switch {
+	case pub.Attributes&tpm2.AttrPPRead != 0:
+		return tpm2.HandlePlatform, nil
+	case pub.Attributes&tpm2.AttrOwnerRead != 0:
+		return tpm2.HandleOwner, nil
+	case pub.Attributes&tpm2.AttrAuthRead != 0:
+		return pub.NVIndex, nil
+	}
+	return 0, ErrPolicyRead
+}
+
+// CountsFailures reports whether a failed read of the index counts towards
+// dictionary attack lockout: it is read with its own authorization value, and
+// does not have TPMA_NV_NO_DA set.
+func CountsFailures(pub tpm2.NVPublic) bool {
+	authHandle, err := readAuth(pub)
+	return err == nil && authHandle == pub.NVIndex && pub.Attributes&tpm2.AttrNoDA == 0
+}
+
+// Read reads the whole contents of the index, with an empty authorization
+// value, in blocks of at most blockSize bytes (TPM_PT_NV_BUFFER_MAX).
+func Read(tpm io.ReadWriter, pub tpm2.NVPublic, blockSize int) ([]byte, error) {
+	if pub.Attributes&tpm2.AttrWritten == 0 {
+		return nil, ErrNotWritten
+	}
+	if pub.Attributes&tpm2.AttrReadLocked != 0 {
+		return nil, ErrReadLocked
+	}
+	authHandle, err := readAuth(pub)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]byte, 0, pub.DataSize)
+	for len(result) < int(pub.DataSize) {
+		size := int(pub.DataSize) - len(result)
+		if size > blockSize {
+			size = blockSize
+		}
+		handles := []auth.Handle{
+			{Handle: authHandle, Session: auth.Password(nil)},
+			{Handle: pub.NVIndex},
+		}
+		resp, err := auth.Run(tpm, tpm2.CmdReadNV, handles, uint16(size), uint16(len(result)))
+		if err != nil {
+			return nil, err
+		}
+		var data tpmutil.U16Bytes
+		if _, err := tpmutil.Unpack(resp, &data); err != nil {
+			return nil, fmt.Errorf("could not decode TPM2_NV_Read response: %w", err)
+		}
+		if len(data) != size {
+			return nil, fmt.Errorf("TPM2_NV_Read returned %d bytes instead of %d", len(data), size)
+		}
+		result = append(result, data...)
+	}
+	return result, nil
+}
+
+// Value decodes the contents of a counter, bit field or PIN index, e.g.
+// "42" for a counter. It returns false for the other types.
+func Value(t Type, data []byte) (string, bool) {
+	switch t {
+	case TypeCounter, TypeBits:
+		if len(data) != 8 {
+			return "", false
+		}
+		value := binary.BigEndian.Uint64(data)
+		if t == TypeBits {
+			return fmt.Sprintf("0x%x", value), true
+		}
+		return fmt.Sprintf("%d", value), true
+	case TypePinFail, TypePinPass:
+		if len(data) != 8 {
+			return "", false
+		}
+		count, limit := binary.BigEndian.Uint32(data), binary.BigEndian.Uint32(data[4:])
+		if t == TypePinFail {
+			return fmt.Sprintf("%d of %d failures", count, limit), true
+		}
+		return fmt.Sprintf("%d of %d uses", count, limit), true
+	}
+	return "", false
+}
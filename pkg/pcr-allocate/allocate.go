@@ -2,157 +2,250 @@ package pcrAllocate
 
 import (
 	"bytes"
-	"encoding/binary"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
 
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/chrisfenner/tpm-top/pkg/auth"
+	"github.com/chrisfenner/tpm-top/pkg/caps"
+	"github.com/chrisfenner/tpm-top/pkg/cc"
 	"github.com/chrisfenner/tpm-top/pkg/pcrs"
 	"github.com/google/go-tpm/tpm2"
 	"github.com/google/go-tpm/tpmutil"
 )
 
-const (
-	cmdPcrAllocate tpmutil.Command = 0x12b
-	rhPlatform     tpmutil.Handle  = 0x4000000c
-)
+// algorithmHash is the hash bit of TPMA_ALGORITHM.
+const algorithmHash tpm2.AlgorithmAttributes = 1 << 2
 
-// Removes items that are present in both lists of algorithms.
-// Useful for removing redundant PCR bank selections below.
-func dedup(l1, l2 []tpm2.Algorithm) ([]tpm2.Algorithm, []tpm2.Algorithm) {
-	counts := make(map[tpm2.Algorithm]int)
-	for _, alg := range l1 {
-		counts[alg]++
-	}
-	for _, alg := range l2 {
-		counts[alg]++
-	}
-	r1 := make([]tpm2.Algorithm, 0)
-	for _, alg := range l1 {
-		if counts[alg] != 2 {
-			r1 = append(r1, alg)
-		}
-	}
-	r2 := make([]tpm2.Algorithm, 0)
-	for _, alg := range l2 {
-		if counts[alg] != 2 {
-			r2 = append(r2, alg)
-		}
-	}
-	return r1, r2
+// BankChange is a change in the allocation of one PCR bank.
+type BankChange struct {
+	// Hash is the hash algorithm of the bank.
+	Hash tpm2.Algorithm
+	// Current are the PCRs allocated now, in ascending order.
+	Current []int
+	// Proposed are the PCRs to allocate, in ascending order.
+	Proposed []int
 }
 
-type pcrAllocateResponse struct {
-	AllocationSuccess uint8
-	MaxPcr            uint32
-	SizeNeeded        uint32
-	SizeAvailable     uint32
+// Plan is a proposed PCR allocation, and how it differs from the current one.
+type Plan struct {
+	// Layout is the TPM's PCR layout.
+	Layout pcrs.Layout
+	// Banks are the banks to allocate, with the PCRs to allocate in each.
+	// All other banks are deallocated.
+	Banks []tpm2.PCRSelection
+	// Changes are the banks whose allocation changes, in the order the TPM
+	// reports them and then in the order of Banks.
+	Changes []BankChange
+	// current is the current allocation, including empty banks.
+	current []tpm2.PCRSelection
 }
 
-// PcrAllocate allocates 24 PCRs for each of the given algorithms.
-// Requires physical presence.
-func PcrAllocate(tpm io.ReadWriter, algs []tpm2.Algorithm) error {
-	// TPM2_PCR_ALLOCATE doesn't do anything to current PCR banks
-	// that are not explicitly mentioned in the command.
-	activeAlgs, err := pcrs.GetAlgorithms(tpm)
-	if err != nil {
-		return err
-	}
-	activeAlgs, algs = dedup(activeAlgs, algs)
-	auth, err := encodeAuth()
-	if err != nil {
-		return err
-	}
-	parms, err := encodePcrSelections(activeAlgs, algs)
-	if err != nil {
-		return err
+// ParseAllocation parses PCR allocations like "SHA256: 0-23, SHA1: 0-7" or
+// "sha256:0-23+sha1:0-7". A hash algorithm on its own, like "sha256", means
+// all of the PCRs in the layout.
+func ParseAllocation(layout pcrs.Layout, specs ...string) ([]tpm2.PCRSelection, error) {
+	result := make([]tpm2.PCRSelection, 0)
+	for _, spec := range specs {
+		var sels []tpm2.PCRSelection
+		if strings.Contains(spec, ":") {
+			var err error
+			sels, err = pcrs.ParseSelection(spec)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			a, err := alg.LookupHash(strings.TrimSpace(spec))
+			if err != nil {
+				return nil, err
+			}
+			sels = []tpm2.PCRSelection{{Hash: a.ID, PCRs: layout.All()}}
+		}
+		for _, sel := range sels {
+			for _, other := range result {
+				if other.Hash == sel.Hash {
+					return nil, fmt.Errorf("the %s bank is given more than once", alg.Name(sel.Hash))
+				}
+			}
+			result = append(result, sel)
+		}
 	}
-	rsp, code, err := tpmutil.RunCommand(tpm, tpm2.TagSessions, cmdPcrAllocate, rhPlatform, tpmutil.RawBytes(auth), tpmutil.RawBytes(parms))
-	if err != nil {
-		return err
-	}
-	if code != 0 {
-		return fmt.Errorf("TPM2_PCR_ALLOCATE returned 0x%x", code)
-	}
-	// Strangely, we see an extra 4 bytes at the front of the response
-	// and an extra 5 at the end.
-	// The 4 seem to match the exact size of the expected response structure (0x0000000d).
-	// The 5 don't make sense.
-	// Otherwise, the data we see is exactly what we expect from the simulator.
-	// TODO: make sense of this.
-	reader := bytes.NewReader(rsp)
-	var parsedSize uint32
-	if err := binary.Read(reader, binary.BigEndian, &parsedSize); err != nil {
-		return err
-	}
-	var parsedRsp pcrAllocateResponse
-	if err := binary.Read(reader, binary.BigEndian, &parsedRsp); err != nil {
-		return err
-	}
-	if parsedRsp.AllocationSuccess != 1 {
-		return fmt.Errorf("TPM2_PCR_ALLOCATE returned RC_SUCCESS, but the following information:\n%+v\n", parsedRsp)
-	}
-	return nil
+	return result, nil
 }
 
-// encodeAuth encodes a TPM 2.0 command auth area with a single password session using the empty Platform auth value.
-func encodeAuth() ([]byte, error) {
-	auth := tpm2.AuthCommand{
-		Session:    tpm2.HandlePasswordSession,
-		Attributes: tpm2.SessionAttributes(0),
-		Auth:       tpm2.EmptyAuth,
+// NewPlan checks a PCR allocation against the TPM's capabilities, and works
+// out how it differs from the current allocation. Banks that are not given
+// are deallocated.
+func NewPlan(tpm io.ReadWriter, banks []tpm2.PCRSelection) (*Plan, error) {
+	layout, err := pcrs.GetLayout(tpm)
+	if err != nil {
+		return nil, err
 	}
-	authBuf, err := tpmutil.Pack(auth)
+	current, err := pcrs.GetBanks(tpm)
 	if err != nil {
 		return nil, err
 	}
-	size, err := tpmutil.Pack(uint32(len(authBuf)))
+	algs, err := caps.Algorithms(tpm)
 	if err != nil {
 		return nil, err
 	}
-	authBuf = append(size, authBuf...)
-	return authBuf, nil
+	hashes := make(map[tpm2.Algorithm]bool)
+	for _, a := range algs {
+		if a.Attributes&algorithmHash != 0 {
+			hashes[a.ID] = true
+		}
+	}
+	return newPlan(layout, current, hashes, banks)
 }
 
-// encodePcrSelections encodes a TPML_PCR_SELECTION of 24 PCRs in each of the given algorithms.
-func encodePcrSelections(remove, add []tpm2.Algorithm) ([]byte, error) {
-	selection := &bytes.Buffer{}
-	// TPML_PCR_SELECTION.count
-	if err := binary.Write(selection, binary.BigEndian, uint32(len(add)+len(remove))); err != nil {
-		return nil, err
-	}
-	// TPML_PCR_SELECTION.pcrSelections
-	for _, alg := range remove {
-		// TPMS_PCR_SELECTION.hash
-		if err := binary.Write(selection, binary.BigEndian, alg); err != nil {
-			return nil, err
+// newPlan checks a PCR allocation against the TPM's PCR layout and hash
+// algorithms, and compares it with the current allocation.
+func newPlan(layout pcrs.Layout, current []tpm2.PCRSelection, hashes map[tpm2.Algorithm]bool, banks []tpm2.PCRSelection) (*Plan, error) {
+	for _, bank := range banks {
+		if !hashes[bank.Hash] {
+			return nil, fmt.Errorf("the TPM does not implement the hash algorithm %s (TPM_CAP_ALGS)", alg.Name(bank.Hash))
 		}
-		// TPMS_PCR_SELECTION.sizeOfSelect
-		if err := binary.Write(selection, binary.BigEndian, uint8(3)); err != nil {
-			return nil, err
+		for _, pcr := range bank.PCRs {
+			if pcr < 0 || pcr >= layout.Count {
+				return nil, fmt.Errorf("%s PCR %d is out of range (the TPM has %d PCRs)", alg.Name(bank.Hash), pcr, layout.Count)
+			}
 		}
-		// TPMS_PCR_SELECTION.pcrSelect
-		for i := 0; i < 3; i++ {
-			if err := binary.Write(selection, binary.BigEndian, uint8(0x00)); err != nil {
-				return nil, err
+	}
+	return &Plan{
+		Layout:  layout,
+		Banks:   banks,
+		Changes: changes(current, banks),
+		current: current,
+	}, nil
+}
+
+// changes compares the current and proposed allocations.
+func changes(current, proposed []tpm2.PCRSelection) []BankChange {
+	result := make([]BankChange, 0)
+	seen := make(map[tpm2.Algorithm]bool)
+	for _, sels := range [][]tpm2.PCRSelection{current, proposed} {
+		for _, sel := range sels {
+			if seen[sel.Hash] {
+				continue
+			}
+			seen[sel.Hash] = true
+			change := BankChange{
+				Hash:     sel.Hash,
+				Current:  indices(current, sel.Hash),
+				Proposed: indices(proposed, sel.Hash),
+			}
+			if !equal(change.Current, change.Proposed) {
+				result = append(result, change)
 			}
 		}
 	}
-	// TPML_PCR_SELECTION.pcrSelections
-	for _, alg := range add {
-		// TPMS_PCR_SELECTION.hash
-		if err := binary.Write(selection, binary.BigEndian, alg); err != nil {
-			return nil, err
+	return result
+}
+
+// indices returns the PCRs selected in the bank, in ascending order.
+func indices(sels []tpm2.PCRSelection, hash tpm2.Algorithm) []int {
+	selected := make(map[int]bool)
+	for _, sel := range sels {
+		if sel.Hash != hash {
+			continue
 		}
-		// TPMS_PCR_SELECTION.sizeOfSelect
-		if err := binary.Write(selection, binary.BigEndian, uint8(3)); err != nil {
-			return nil, err
+		for _, pcr := range sel.PCRs {
+			selected[pcr] = true
 		}
-		// TPMS_PCR_SELECTION.pcrSelect
-		for i := 0; i < 3; i++ {
-			if err := binary.Write(selection, binary.BigEndian, uint8(0xff)); err != nil {
-				return nil, err
-			}
+	}
+	result := make([]int, 0, len(selected))
+	for pcr := range selected {
+		result = append(result, pcr)
+	}
+	sort.Ints(result)
+	return result
+}
+
+// has reports whether the bank is selected, even if with no PCRs.
+func has(sels []tpm2.PCRSelection, hash tpm2.Algorithm) bool {
+	for _, sel := range sels {
+		if sel.Hash == hash {
+			return true
+		}
+	}
+	return false
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// SizeNeeded returns the PCR memory in bytes the proposed allocation needs.
+func (p *Plan) SizeNeeded() int {
+	size := 0
+	for _, bank := range p.Banks {
+		if a, ok := alg.ByID(bank.Hash); ok {
+			size += len(indices(p.Banks, bank.Hash)) * a.DigestSize
+		}
+	}
+	return size
+}
+
+// Apply sends TPM2_PCR_Allocate with the proposed allocation, authorized with
+// the platform session. Requires physical presence. The TPM may accept the
+// command but refuse the allocation, which the result reports.
+func (p *Plan) Apply(tpm io.ReadWriter, platform auth.Session) (*Result, error) {
+	// TPM2_PCR_ALLOCATE doesn't do anything to PCR banks that are not
+	// explicitly mentioned in the command, and replaces any allocation that
+	// has not taken effect yet, which TPM_CAP_PCRS does not show. So send
+	// every bank, even if it does not change.
+	sels := append([]tpm2.PCRSelection{}, p.Banks...)
+	for _, bank := range p.current {
+		if !has(sels, bank.Hash) {
+			sels = append(sels, tpm2.PCRSelection{Hash: bank.Hash})
 		}
 	}
-	return selection.Bytes(), nil
+	parms, err := p.Layout.EncodeSelection(sels...)
+	if err != nil {
+		return nil, err
+	}
+	handles := []auth.Handle{{Handle: tpm2.HandlePlatform, Session: platform}}
+	rsp, err := auth.Run(tpm, cc.PCRAllocate, handles, tpmutil.RawBytes(parms))
+	if err != nil {
+		return nil, err
+	}
+	return decodeResponse(rsp)
+}
+
+// Result is the response of TPM2_PCR_Allocate.
+type Result struct {
+	// AllocationSuccess is set if the allocation will take effect at the
+	// next TPM reset.
+	AllocationSuccess bool
+	// MaxPCR is the number of PCRs in each bank.
+	MaxPCR uint32
+	// SizeNeeded is the number of octets of PCR memory the allocation needs.
+	SizeNeeded uint32
+	// SizeAvailable is the number of octets of PCR memory the TPM has.
+	SizeAvailable uint32
+}
+
+// decodeResponse decodes the response parameters of TPM2_PCR_Allocate.
+func decodeResponse(rsp []byte) (*Result, error) {
+	var allocationSuccess uint8
+	var result Result
+	buf := bytes.NewBuffer(rsp)
+	if err := tpmutil.UnpackBuf(buf, &allocationSuccess, &result.MaxPCR, &result.SizeNeeded, &result.SizeAvailable); err != nil {
+		return nil, fmt.Errorf("could not decode TPM2_PCR_Allocate response parameters: %w", err)
+	}
+	if buf.Len() != 0 {
+		return nil, fmt.Errorf("%d unexpected bytes after the TPM2_PCR_Allocate response parameters", buf.Len())
+	}
+	result.AllocationSuccess = allocationSuccess != 0
+	return &result, nil
 }
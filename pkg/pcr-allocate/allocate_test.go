@@ -0,0 +1,67 @@
+package pcrAllocate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chrisfenner/tpm-top/pkg/pcrs"
+	"github.com/google/go-tpm/tpm2"
+)
+
+func TestNewPlan(t *testing.T) {
+	layout := pcrs.Layout{Count: 24, SizeOfSelect: 3}
+	hashes := map[tpm2.Algorithm]bool{tpm2.AlgSHA1: true, tpm2.AlgSHA256: true, tpm2.AlgSHA384: true}
+	current := []tpm2.PCRSelection{
+		{Hash: tpm2.AlgSHA1, PCRs: layout.All()},
+		{Hash: tpm2.AlgSHA256, PCRs: []int{0, 1, 2, 3, 4, 5, 6, 7}},
+		{Hash: tpm2.AlgSHA384},
+	}
+	for _, test := range []struct {
+		name    string
+		banks   []tpm2.PCRSelection
+		changes []BankChange
+		size    int
+	}{
+		{
+			name:    "unchanged",
+			banks:   current[:2],
+			changes: []BankChange{},
+			size:    24*20 + 8*32,
+		},
+		{
+			name:  "deallocate sha1, grow sha256, allocate sha384",
+			banks: []tpm2.PCRSelection{{Hash: tpm2.AlgSHA256, PCRs: layout.All()}, {Hash: tpm2.AlgSHA384, PCRs: []int{23}}},
+			changes: []BankChange{
+				{Hash: tpm2.AlgSHA1, Current: layout.All(), Proposed: []int{}},
+				{Hash: tpm2.AlgSHA256, Current: []int{0, 1, 2, 3, 4, 5, 6, 7}, Proposed: layout.All()},
+				{Hash: tpm2.AlgSHA384, Current: []int{}, Proposed: []int{23}},
+			},
+			size: 24*32 + 48,
+		},
+	} {
+		plan, err := newPlan(layout, current, hashes, test.banks)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(plan.Changes, test.changes) {
+			t.Errorf("%s: changes = %v, want %v", test.name, plan.Changes, test.changes)
+		}
+		if size := plan.SizeNeeded(); size != test.size {
+			t.Errorf("%s: SizeNeeded() = %d, want %d", test.name, size, test.size)
+		}
+	}
+}
+
+func TestNewPlanErrors(t *testing.T) {
+	layout := pcrs.Layout{Count: 24, SizeOfSelect: 3}
+	hashes := map[tpm2.Algorithm]bool{tpm2.AlgSHA256: true}
+	for _, banks := range [][]tpm2.PCRSelection{
+		{{Hash: tpm2.AlgSHA512, PCRs: []int{0}}},
+		{{Hash: tpm2.AlgSHA256, PCRs: []int{24}}},
+	} {
+		if _, err := newPlan(layout, nil, hashes, banks); err == nil {
+			t.Errorf("newPlan(%v) succeeded, want an error", banks)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package pcrs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/google/go-tpm/tpm2"
+)
+
+const (
+	// formatVersion is the version of the JSON and binary snapshot formats.
+	formatVersion = 1
+	// binaryMagic starts every binary snapshot.
+	binaryMagic = "PCRS"
+)
+
+// snapshotJSON is the JSON form of a Snapshot.
+type snapshotJSON struct {
+	Version       int        `json:"version"`
+	Time          time.Time  `json:"time"`
+	UpdateCounter uint32     `json:"updateCounter"`
+	Banks         []bankJSON `json:"banks"`
+}
+
+// bankJSON is the JSON form of a Bank.
+type bankJSON struct {
+	Hash string    `json:"hash"`
+	PCRs []pcrJSON `json:"pcrs"`
+}
+
+// pcrJSON is the JSON form of a single PCR value.
+type pcrJSON struct {
+	Index  int    `json:"index"`
+	Digest string `json:"digest"`
+}
+
+// hashName returns the name of a bank's hash algorithm in the snapshot
+// formats, e.g. "sha2-256", or its hex value if it is not known.
+func hashName(hash tpm2.Algorithm) string {
+	if a, ok := alg.ByID(hash); ok {
+		return a.Aliases[0]
+	}
+	return fmt.Sprintf("0x%04x", uint16(hash))
+}
+
+// parseHashName parses the name of a bank's hash algorithm.
+func parseHashName(name string) (tpm2.Algorithm, error) {
+	if strings.HasPrefix(name, "0x") {
+		id, err := strconv.ParseUint(name, 0, 16)
+		if err != nil {
+			return 0, fmt.Errorf("invalid hash algorithm %q", name)
+		}
+		return tpm2.Algorithm(id), nil
+	}
+	a, err := alg.LookupHash(name)
+	if err != nil {
+		return 0, err
+	}
+	return a.ID, nil
+}
+
+// Indices returns the indices of the PCRs in the bank, in ascending order.
+func (b *Bank) Indices() []int {
+	result := make([]int, 0, len(b.PCRs))
+	for pcr := range b.PCRs {
+		result = append(result, pcr)
+	}
+	sort.Ints(result)
+	return result
+}
+
+// MarshalJSON encodes the snapshot as JSON, with the banks in order and the
+// PCRs in each bank in ascending order.
+func (s *Snapshot) MarshalJSON() ([]byte, error) {
+	out := snapshotJSON{
+		Version:       formatVersion,
+		Time:          s.Time,
+		UpdateCounter: s.UpdateCounter,
+		Banks:         make([]bankJSON, 0, len(s.Banks)),
+	}
+	for i := range s.Banks {
+		bank := &s.Banks[i]
+		b := bankJSON{
+			Hash: hashName(bank.Hash),
+			PCRs: make([]pcrJSON, 0, len(bank.PCRs)),
+		}
+		for _, pcr := range bank.Indices() {
+			b.PCRs = append(b.PCRs, pcrJSON{
+				Index:  pcr,
+				Digest: hex.EncodeToString(bank.PCRs[pcr]),
+			})
+		}
+		out.Banks = append(out.Banks, b)
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a snapshot encoded by MarshalJSON.
+func (s *Snapshot) UnmarshalJSON(data []byte) error {
+	var in snapshotJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in.Version != formatVersion {
+		return fmt.Errorf("unsupported snapshot version %d", in.Version)
+	}
+	result := Snapshot{
+		Time:          in.Time,
+		UpdateCounter: in.UpdateCounter,
+		Banks:         make([]Bank, 0, len(in.Banks)),
+	}
+	for _, b := range in.Banks {
+		hash, err := parseHashName(b.Hash)
+		if err != nil {
+			return err
+		}
+		bank := Bank{
+			Hash: hash,
+			PCRs: make(map[int][]byte),
+		}
+		for _, pcr := range b.PCRs {
+			digest, err := hex.DecodeString(pcr.Digest)
+			if err != nil {
+				return fmt.Errorf("invalid digest for %s PCR %d: %w", b.Hash, pcr.Index, err)
+			}
+			bank.PCRs[pcr.Index] = digest
+		}
+		result.Banks = append(result.Banks, bank)
+	}
+	*s = result
+	return nil
+}
+
+// binaryHeader is the fixed part of a binary snapshot, after the magic.
+type binaryHeader struct {
+	Version       uint8
+	Time          int64
+	UpdateCounter uint32
+	BankCount     uint16
+}
+
+// binaryBank is the fixed part of each bank in a binary snapshot, followed by
+// PCRCount PCRs, each a uint16 index and a digest of the bank's digest size.
+type binaryBank struct {
+	Hash       uint16
+	DigestSize uint16
+	PCRCount   uint16
+}
+
+// MarshalBinary encodes the snapshot in a compact binary form.
+func (s *Snapshot) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(binaryMagic)
+	header := binaryHeader{
+		Version:       formatVersion,
+		Time:          s.Time.UnixNano(),
+		UpdateCounter: s.UpdateCounter,
+		BankCount:     uint16(len(s.Banks)),
+	}
+	if err := binary.Write(&buf, binary.BigEndian, header); err != nil {
+		return nil, err
+	}
+	for i := range s.Banks {
+		bank := &s.Banks[i]
+		indices := bank.Indices()
+		size := 0
+		if len(indices) != 0 {
+			size = len(bank.PCRs[indices[0]])
+		}
+		b := binaryBank{
+			Hash:       uint16(bank.Hash),
+			DigestSize: uint16(size),
+			PCRCount:   uint16(len(indices)),
+		}
+		if err := binary.Write(&buf, binary.BigEndian, b); err != nil {
+			return nil, err
+		}
+		for _, pcr := range indices {
+			if len(bank.PCRs[pcr]) != size {
+				return nil, fmt.Errorf("%s PCR %d has a %d-byte digest, expected %d bytes", hashName(bank.Hash), pcr, len(bank.PCRs[pcr]), size)
+			}
+			if err := binary.Write(&buf, binary.BigEndian, uint16(pcr)); err != nil {
+				return nil, err
+			}
+			buf.Write(bank.PCRs[pcr])
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a snapshot encoded by MarshalBinary.
+func (s *Snapshot) UnmarshalBinary(data []byte) error {
+	if !bytes.HasPrefix(data, []byte(binaryMagic)) {
+		return errors.New("not a binary PCR snapshot")
+	}
+	r := bytes.NewReader(data[len(binaryMagic):])
+	var header binaryHeader
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return fmt.Errorf("could not read snapshot header: %w", err)
+	}
+	if header.Version != formatVersion {
+		return fmt.Errorf("unsupported snapshot version %d", header.Version)
+	}
+	result := Snapshot{
+		Time:          time.Unix(0, header.Time),
+		UpdateCounter: header.UpdateCounter,
+		Banks:         make([]Bank, 0, header.BankCount),
+	}
+	for i := 0; i < int(header.BankCount); i++ {
+		var b binaryBank
+		if err := binary.Read(r, binary.BigEndian, &b); err != nil {
+			return fmt.Errorf("could not read bank %d: %w", i, err)
+		}
+		bank := Bank{
+			Hash: tpm2.Algorithm(b.Hash),
+			PCRs: make(map[int][]byte),
+		}
+		for j := 0; j < int(b.PCRCount); j++ {
+			var pcr uint16
+			if err := binary.Read(r, binary.BigEndian, &pcr); err != nil {
+				return fmt.Errorf("could not read %s PCR: %w", hashName(bank.Hash), err)
+			}
+			digest := make([]byte, b.DigestSize)
+			if _, err := io.ReadFull(r, digest); err != nil {
+				return fmt.Errorf("could not read %s PCR %d: %w", hashName(bank.Hash), pcr, err)
+			}
+			bank.PCRs[int(pcr)] = digest
+		}
+		result.Banks = append(result.Banks, bank)
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%d unexpected bytes after the snapshot", r.Len())
+	}
+	*s = result
+	return nil
+}
+
+// ParseSnapshot decodes a snapshot in the JSON, binary or tpm2_pcrread YAML
+// form.
+func ParseSnapshot(data []byte) (*Snapshot, error) {
+	var s Snapshot
+	if bytes.HasPrefix(data, []byte(binaryMagic)) {
+		if err := s.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+		return &s, nil
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return ParseYAML(data)
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("could not parse PCR snapshot: %w", err)
+	}
+	return &s, nil
+}
+
+// Change is a PCR whose value differs between two snapshots.
+type Change struct {
+	// Hash is the hash algorithm of the PCR's bank.
+	Hash tpm2.Algorithm
+	// Index is the index of the PCR.
+	Index int
+	// Old is the value in the first snapshot, or nil if it has no such PCR.
+	Old []byte
+	// New is the value in the second snapshot, or nil if it has no such PCR.
+	New []byte
+}
+
+// Diff returns the PCRs that differ between the snapshots, including PCRs in
+// banks only one of them has. They are ordered by bank, as in a and then b,
+// and then by index.
+func Diff(a, b *Snapshot) []Change {
+	hashes := make([]tpm2.Algorithm, 0)
+	seen := make(map[tpm2.Algorithm]bool)
+	for _, s := range []*Snapshot{a, b} {
+		for _, bank := range s.Banks {
+			if !seen[bank.Hash] {
+				seen[bank.Hash] = true
+				hashes = append(hashes, bank.Hash)
+			}
+		}
+	}
+	result := make([]Change, 0)
+	for _, hash := range hashes {
+		oldBank, newBank := a.Bank(hash), b.Bank(hash)
+		indices := make(map[int]bool)
+		for _, bank := range []*Bank{oldBank, newBank} {
+			if bank == nil {
+				continue
+			}
+			for pcr := range bank.PCRs {
+				indices[pcr] = true
+			}
+		}
+		sorted := make([]int, 0, len(indices))
+		for pcr := range indices {
+			sorted = append(sorted, pcr)
+		}
+		sort.Ints(sorted)
+		for _, pcr := range sorted {
+			var oldValue, newValue []byte
+			if oldBank != nil {
+				oldValue = oldBank.PCRs[pcr]
+			}
+			if newBank != nil {
+				newValue = newBank.PCRs[pcr]
+			}
+			if oldValue == nil || newValue == nil || !bytes.Equal(oldValue, newValue) {
+				result = append(result, Change{
+					Hash:  hash,
+					Index: pcr,
+					Old:   oldValue,
+					New:   newValue,
+				})
+			}
+		}
+	}
+	return result
+}
+
+// Check compares a snapshot against golden values, which may list only some
+// banks and PCRs. It returns the golden PCRs whose value in the snapshot is
+// different or missing.
+func Check(golden, s *Snapshot) []Change {
+	result := make([]Change, 0)
+	for i := range golden.Banks {
+		want := &golden.Banks[i]
+		got := s.Bank(want.Hash)
+		for _, pcr := range want.Indices() {
+			var value []byte
+			if got != nil {
+				value = got.PCRs[pcr]
+			}
+			if value == nil || !bytes.Equal(value, want.PCRs[pcr]) {
+				result = append(result, Change{
+					Hash:  want.Hash,
+					Index: pcr,
+					Old:   want.PCRs[pcr],
+					New:   value,
+				})
+			}
+		}
+	}
+	return result
+}
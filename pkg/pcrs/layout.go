@@ -0,0 +1,135 @@
+package pcrs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/chrisfenner/tpm-top/pkg/caps"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// Layout describes the PCRs the TPM implements.
+type Layout struct {
+	// Count is the number of PCRs in each bank (TPM_PT_PCR_COUNT).
+	Count int
+	// SizeOfSelect is the size in bytes of the pcrSelect bitmaps sent to the
+	// TPM. It is at least TPM_PT_PCR_SELECT_MIN and large enough for Count.
+	SizeOfSelect int
+}
+
+// PCClientLayout is the layout of a TPM following the PC Client platform
+// specification, with 24 PCRs, for use when no TPM is available.
+var PCClientLayout = Layout{
+	Count:        24,
+	SizeOfSelect: 3,
+}
+
+// GetLayout queries the TPM for the number of PCRs it implements.
+func GetLayout(tpm io.ReadWriter) (Layout, error) {
+	count, err := caps.Property(tpm, tpm2.PCRCount)
+	if err != nil {
+		return Layout{}, err
+	}
+	selectMin, err := caps.Property(tpm, tpm2.PCRSelectMin)
+	if err != nil {
+		return Layout{}, err
+	}
+	size := int(selectMin)
+	if needed := (int(count) + 7) / 8; needed > size {
+		size = needed
+	}
+	return Layout{
+		Count:        int(count),
+		SizeOfSelect: size,
+	}, nil
+}
+
+// All returns the indices of all the PCRs.
+func (l Layout) All() []int {
+	result := make([]int, l.Count)
+	for i := range result {
+		result[i] = i
+	}
+	return result
+}
+
+// Full reports whether every PCR is selected.
+func (l Layout) Full(sel tpm2.PCRSelection) bool {
+	selected := make(map[int]bool)
+	for _, pcr := range sel.PCRs {
+		if pcr >= 0 && pcr < l.Count {
+			selected[pcr] = true
+		}
+	}
+	return len(selected) == l.Count
+}
+
+// EncodeSelection encodes a TPML_PCR_SELECTION. Unlike go-tpm, it supports
+// any number of PCRs, and encodes selections with no PCRs selected.
+func (l Layout) EncodeSelection(sels ...tpm2.PCRSelection) ([]byte, error) {
+	var buf bytes.Buffer
+	count, err := tpmutil.Pack(uint32(len(sels)))
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(count)
+	for _, sel := range sels {
+		bitmap := make([]byte, l.SizeOfSelect)
+		for _, pcr := range sel.PCRs {
+			if pcr < 0 || pcr >= l.Count {
+				return nil, fmt.Errorf("PCR index %d is out of range (the TPM has %d PCRs)", pcr, l.Count)
+			}
+			bitmap[pcr/8] |= 1 << uint(pcr%8)
+		}
+		packed, err := tpmutil.Pack(sel.Hash, uint8(len(bitmap)), tpmutil.RawBytes(bitmap))
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(packed)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeSelection decodes a TPML_PCR_SELECTION of any size.
+func decodeSelection(buf *bytes.Buffer) ([]tpm2.PCRSelection, error) {
+	var count uint32
+	if err := tpmutil.UnpackBuf(buf, &count); err != nil {
+		return nil, fmt.Errorf("could not decode TPML_PCR_SELECTION: %w", err)
+	}
+	result := make([]tpm2.PCRSelection, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var hash tpm2.Algorithm
+		var size uint8
+		if err := tpmutil.UnpackBuf(buf, &hash, &size); err != nil {
+			return nil, fmt.Errorf("could not decode TPMS_PCR_SELECTION: %w", err)
+		}
+		bitmap := buf.Next(int(size))
+		if len(bitmap) != int(size) {
+			return nil, fmt.Errorf("could not decode TPMS_PCR_SELECTION: pcrSelect is truncated")
+		}
+		sel := tpm2.PCRSelection{
+			Hash: hash,
+			PCRs: make([]int, 0),
+		}
+		for j, b := range bitmap {
+			for k := 0; k < 8; k++ {
+				if b&(1<<uint(k)) != 0 {
+					sel.PCRs = append(sel.PCRs, 8*j+k)
+				}
+			}
+		}
+		result = append(result, sel)
+	}
+	return result, nil
+}
+
+// sortedPCRs returns the PCR indices of the selection in ascending order.
+func sortedPCRs(sel tpm2.PCRSelection) []int {
+	result := make([]int, len(sel.PCRs))
+	copy(result, sel.PCRs)
+	sort.Ints(result)
+	return result
+}
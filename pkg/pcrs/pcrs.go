@@ -1,26 +1,63 @@
 package pcrs
 
 import (
-	"github.com/google/go-tpm/tpm2"
+	"fmt"
 	"io"
+
+	"github.com/chrisfenner/tpm-top/pkg/rc"
+	"github.com/google/go-tpm/tpm2"
 )
 
-// GetAlgorithms gets all the active PCR algorithms on the TPM.
+// maxBanks is the number of PCR banks requested at a time. It is larger than
+// the number of hash algorithms any TPM implements.
+const maxBanks = 16
+
+// GetBanks gets the PCR allocation of every PCR bank the TPM implements
+// (TPM_CAP_PCRS), including banks with no PCRs allocated.
+func GetBanks(tpm io.ReadWriter) ([]tpm2.PCRSelection, error) {
+	result := make([]tpm2.PCRSelection, 0)
+	seen := make(map[tpm2.Algorithm]bool)
+	next := uint32(0)
+	for {
+		// The specification says that the TPM always reports the full
+		// allocation, but page through it in case a TPM sets moreData.
+		sels, more, err := tpm2.GetCapability(tpm, tpm2.CapabilityPCRs, maxBanks, next)
+		if err != nil {
+			return nil, rc.WithCommand(err, tpm2.CmdGetCapability)
+		}
+		added := 0
+		for _, s := range sels {
+			sel, ok := s.(tpm2.PCRSelection)
+			if !ok {
+				return nil, fmt.Errorf("TPM returned %T instead of a PCR selection", s)
+			}
+			// go-tpm reports an empty TPML_PCR_SELECTION as a single
+			// selection of TPM_ALG_ERROR.
+			if sel.Hash == tpm2.AlgUnknown || seen[sel.Hash] {
+				continue
+			}
+			seen[sel.Hash] = true
+			result = append(result, sel)
+			next = uint32(sel.Hash) + 1
+			added++
+		}
+		if !more || added == 0 {
+			return result, nil
+		}
+	}
+}
+
+// GetAlgorithms gets all the active PCR algorithms on the TPM, i.e., the ones
+// with at least one PCR allocated.
 func GetAlgorithms(tpm io.ReadWriter) ([]tpm2.Algorithm, error) {
-	result := make([]tpm2.Algorithm, 0)
-	// If a TPM has more than 8 active PCR banks, we will just have to miss out.
-	// TODO: this more elegantly.
-	sels, _, err := tpm2.GetCapability(tpm, tpm2.CapabilityPCRs, 8, 0)
+	banks, err := GetBanks(tpm)
 	if err != nil {
 		return nil, err
 	}
-	for _, sel := range sels {
-		pcr, ok := sel.(tpm2.PCRSelection)
-		if !ok {
-			return nil, err
-		}
-		if len(pcr.PCRs) > 0 {
-			result = append(result, pcr.Hash)
+	result := make([]tpm2.Algorithm, 0)
+	for _, bank := range banks {
+		if len(bank.PCRs) > 0 {
+			result = append(result, bank.Hash)
 		}
 	}
 	return result, nil
@@ -0,0 +1,106 @@
+package pcrs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/chrisfenner/tpm-top/pkg/rc"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// PCRDigest computes the pcrDigest of TPM2_PolicyPCR: the hash of the
+// selected PCR values from the snapshot, concatenated in the order of the
+// selection and then by ascending index.
+func PCRDigest(hash tpm2.Algorithm, s *Snapshot, sels []tpm2.PCRSelection) ([]byte, error) {
+	a, ok := alg.ByID(hash)
+	if !ok || !a.IsHash() {
+		return nil, fmt.Errorf("%s is not a known hash algorithm", alg.Name(hash))
+	}
+	var values bytes.Buffer
+	for _, sel := range sels {
+		bank := s.Bank(sel.Hash)
+		for _, pcr := range sortedPCRs(sel) {
+			var value []byte
+			if bank != nil {
+				value = bank.PCRs[pcr]
+			}
+			if value == nil {
+				return nil, fmt.Errorf("%s PCR %d has no value", alg.Name(sel.Hash), pcr)
+			}
+			values.Write(value)
+		}
+	}
+	return a.Digest(values.Bytes())
+}
+
+// PolicyPCR computes the policy digest after TPM2_PolicyPCR with the selected
+// PCRs from the snapshot:
+// H(policyDigest || TPM_CC_PolicyPCR || pcrs || pcrDigest). The policy digest
+// is all zeros at the start of a policy.
+func PolicyPCR(hash tpm2.Algorithm, policyDigest []byte, l Layout, s *Snapshot, sels []tpm2.PCRSelection) ([]byte, error) {
+	a, ok := alg.ByID(hash)
+	if !ok || !a.IsHash() {
+		return nil, fmt.Errorf("%s is not a known hash algorithm", alg.Name(hash))
+	}
+	if len(policyDigest) != a.DigestSize {
+		return nil, fmt.Errorf("%v policy digest is %d bytes, expected %d bytes", a, len(policyDigest), a.DigestSize)
+	}
+	pcrDigest, err := PCRDigest(hash, s, sels)
+	if err != nil {
+		return nil, err
+	}
+	selection, err := l.EncodeSelection(sels...)
+	if err != nil {
+		return nil, err
+	}
+	code, err := tpmutil.Pack(tpm2.CmdPolicyPCR)
+	if err != nil {
+		return nil, err
+	}
+	var data bytes.Buffer
+	data.Write(policyDigest)
+	data.Write(code)
+	data.Write(selection)
+	data.Write(pcrDigest)
+	return a.Digest(data.Bytes())
+}
+
+// TrialPolicyPCR has the TPM compute the policy digest of TPM2_PolicyPCR in a
+// trial session with the given hash algorithm. If pcrDigest is empty, the TPM
+// uses its current PCR values.
+func TrialPolicyPCR(tpm io.ReadWriter, hash tpm2.Algorithm, l Layout, sels []tpm2.PCRSelection, pcrDigest []byte) ([]byte, error) {
+	a, ok := alg.ByID(hash)
+	if !ok || !a.IsHash() {
+		return nil, fmt.Errorf("%s is not a known hash algorithm", alg.Name(hash))
+	}
+	nonce := make([]byte, a.DigestSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	session, _, err := tpm2.StartAuthSession(tpm, tpm2.HandleNull, tpm2.HandleNull, nonce, nil, tpm2.SessionTrial, tpm2.AlgNull, hash)
+	if err != nil {
+		return nil, rc.WithCommand(err, tpm2.CmdStartAuthSession)
+	}
+	defer tpm2.FlushContext(tpm, session)
+	selection, err := l.EncodeSelection(sels...)
+	if err != nil {
+		return nil, err
+	}
+	// go-tpm's PolicyPCR only supports a single bank of up to 24 PCRs.
+	_, code, err := tpmutil.RunCommand(tpm, tpm2.TagNoSessions, tpm2.CmdPolicyPCR, session, tpmutil.U16Bytes(pcrDigest), tpmutil.RawBytes(selection))
+	if err != nil {
+		return nil, err
+	}
+	if code != tpmutil.RCSuccess {
+		return nil, rc.MakeCommandError(int(code), tpm2.CmdPolicyPCR)
+	}
+	digest, err := tpm2.PolicyGetDigest(tpm, session)
+	if err != nil {
+		return nil, rc.WithCommand(err, tpm2.CmdPolicyGetDigest)
+	}
+	return digest, nil
+}
@@ -0,0 +1,69 @@
+package pcrs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// filledSnapshot returns a snapshot with the given banks, whose PCRs all
+// have the given byte as their value.
+func filledSnapshot(l Layout, b byte, hashes ...tpm2.Algorithm) *Snapshot {
+	s := &Snapshot{Banks: make([]Bank, 0, len(hashes))}
+	for _, hash := range hashes {
+		a, _ := alg.ByID(hash)
+		bank := Bank{Hash: hash, PCRs: make(map[int][]byte)}
+		for _, pcr := range l.All() {
+			bank.PCRs[pcr] = bytes.Repeat([]byte{b}, a.DigestSize)
+		}
+		s.Banks = append(s.Banks, bank)
+	}
+	return s
+}
+
+func TestPolicyPCR(t *testing.T) {
+	// The digests are from TPM2_PolicyGetDigest in trial sessions on the
+	// reference TPM simulator, given the same pcrDigest.
+	for _, test := range []struct {
+		hash      tpm2.Algorithm
+		selection string
+		value     byte
+		want      string
+	}{
+		{tpm2.AlgSHA256, "sha256:0-7", 0x00, "9a72c2e06a93c453a86efb47532e9c7a91dcab018e675919910c58d6a1a5aa78"},
+		{tpm2.AlgSHA256, "sha1:0,sha256:23", 0xab, "378a06ee96e88e32affb64129e543def2fe3e15b00329f3a34acf9b8ebd740d1"},
+		{tpm2.AlgSHA1, "sha256:16", 0x00, "eab0d71ae6088009cbd0b50729fde69eb453649c"},
+	} {
+		sels, err := ParseSelection(test.selection)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := filledSnapshot(PCClientLayout, test.value, tpm2.AlgSHA1, tpm2.AlgSHA256)
+		a, _ := alg.ByID(test.hash)
+		got, err := PolicyPCR(test.hash, make([]byte, a.DigestSize), PCClientLayout, s, sels)
+		if err != nil {
+			t.Errorf("%v %s: %v", a, test.selection, err)
+			continue
+		}
+		if hex.EncodeToString(got) != test.want {
+			t.Errorf("%v %s: PolicyPCR() = %x, want %s", a, test.selection, got, test.want)
+		}
+	}
+}
+
+func TestPolicyPCRErrors(t *testing.T) {
+	s := filledSnapshot(PCClientLayout, 0, tpm2.AlgSHA256)
+	sels, err := ParseSelection("sha1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PolicyPCR(tpm2.AlgSHA256, make([]byte, 32), PCClientLayout, s, sels); err == nil {
+		t.Errorf("PolicyPCR() of a bank the snapshot does not have succeeded, want an error")
+	}
+	if _, err := PolicyPCR(tpm2.AlgSHA256, make([]byte, 20), PCClientLayout, s, nil); err == nil {
+		t.Errorf("PolicyPCR() with a SHA-1 sized policy digest succeeded, want an error")
+	}
+}
@@ -0,0 +1,104 @@
+package pcrs
+
+import (
+	"fmt"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// Event is a measurement to extend into a PCR.
+type Event struct {
+	// Data, if not nil, is hashed with each bank's hash algorithm and the
+	// digest extended into the bank, as TPM2_PCR_Event does.
+	Data []byte
+	// Digests otherwise holds the digest to extend into each bank, as
+	// TPM2_PCR_Extend takes. Banks with no digest are not changed.
+	Digests map[tpm2.Algorithm][]byte
+}
+
+// digest returns the digest the event extends into a bank, if any.
+func (e Event) digest(hash tpm2.Algorithm) ([]byte, bool, error) {
+	a, ok := alg.ByID(hash)
+	if !ok || !a.IsHash() {
+		return nil, false, fmt.Errorf("%s is not a known hash algorithm", alg.Name(hash))
+	}
+	if e.Data != nil {
+		digest, err := a.Digest(e.Data)
+		if err != nil {
+			return nil, false, err
+		}
+		return digest, true, nil
+	}
+	digest, ok := e.Digests[hash]
+	if !ok {
+		return nil, false, nil
+	}
+	if len(digest) != a.DigestSize {
+		return nil, false, fmt.Errorf("%v digest is %d bytes, expected %d bytes", a, len(digest), a.DigestSize)
+	}
+	return digest, true, nil
+}
+
+// ExtendDigest computes the value of a PCR in the bank after extending the
+// digest into it: H(value || digest).
+func ExtendDigest(hash tpm2.Algorithm, value, digest []byte) ([]byte, error) {
+	a, ok := alg.ByID(hash)
+	if !ok || !a.IsHash() {
+		return nil, fmt.Errorf("%s is not a known hash algorithm", alg.Name(hash))
+	}
+	if len(value) != a.DigestSize {
+		return nil, fmt.Errorf("%v PCR value is %d bytes, expected %d bytes", a, len(value), a.DigestSize)
+	}
+	data := make([]byte, 0, len(value)+len(digest))
+	data = append(data, value...)
+	data = append(data, digest...)
+	return a.Digest(data)
+}
+
+// Predict computes the value of a PCR in the bank after extending the events
+// into it in order, starting from the given value.
+func Predict(hash tpm2.Algorithm, value []byte, events ...Event) ([]byte, error) {
+	for i, e := range events {
+		digest, ok, err := e.digest(hash)
+		if err != nil {
+			return nil, fmt.Errorf("event %d: %w", i, err)
+		}
+		if !ok {
+			continue
+		}
+		value, err = ExtendDigest(hash, value, digest)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// Predict returns a copy of the snapshot with the events extended into the
+// PCR in every bank where it is allocated.
+func (s *Snapshot) Predict(pcr int, events ...Event) (*Snapshot, error) {
+	result := Snapshot{
+		Time:          s.Time,
+		UpdateCounter: s.UpdateCounter,
+		Banks:         make([]Bank, 0, len(s.Banks)),
+	}
+	for _, bank := range s.Banks {
+		b := Bank{
+			Hash: bank.Hash,
+			PCRs: make(map[int][]byte, len(bank.PCRs)),
+		}
+		for i, value := range bank.PCRs {
+			b.PCRs[i] = value
+		}
+		if value, ok := bank.PCRs[pcr]; ok {
+			predicted, err := Predict(bank.Hash, value, events...)
+			if err != nil {
+				return nil, fmt.Errorf("%s PCR %d: %w", alg.Name(bank.Hash), pcr, err)
+			}
+			b.PCRs[pcr] = predicted
+		}
+		result.Banks = append(result.Banks, b)
+	}
+	return &result, nil
+}
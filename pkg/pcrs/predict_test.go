@@ -0,0 +1,88 @@
+package pcrs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// The SHA-1 and SHA-256 values are from TPM2_PCR_Event and TPM2_PCR_Extend
+// on a reset PCR of the reference TPM simulator. It has no SM3 bank, so the
+// SM3 values are from OpenSSL.
+var predictTests = []struct {
+	hash tpm2.Algorithm
+	// event is extended with TPM2_PCR_Event, and digest with TPM2_PCR_Extend.
+	event, digest string
+}{
+	{
+		tpm2.AlgSHA1,
+		"00629997206c7d587b4ed79aabc3db58c32e1492",
+		"6ea3708120ade24f4718d3ec72a53ecd5b04f3a9",
+	},
+	{
+		tpm2.AlgSHA256,
+		"9851312028952521510e8eaab5be94e7dc24b5fc292b2e9781173cf11ffa9878",
+		"debb3e7acfff6dd18d501042273629f0b79cb206bb8c24f59f62ddb80849403b",
+	},
+	{
+		alg.AlgSM3_256,
+		"b3930aa63d683184a8730a086efddc02b1f81f07f820f132429939790967c785",
+		"541bab1ba419e1f960dffff5f9c374004cfc15ce84293cea9462e7c90a6d787f",
+	},
+}
+
+func TestPredict(t *testing.T) {
+	for _, test := range predictTests {
+		a, _ := alg.ByID(test.hash)
+		zero := make([]byte, a.DigestSize)
+		event := Event{Data: []byte("hello")}
+		if got, err := Predict(test.hash, zero, event); err != nil {
+			t.Errorf("%v event: %v", a, err)
+		} else if hex.EncodeToString(got) != test.event {
+			t.Errorf("%v event: Predict() = %x, want %s", a, got, test.event)
+		}
+		digest := Event{Digests: map[tpm2.Algorithm][]byte{test.hash: bytes.Repeat([]byte{0xab}, a.DigestSize)}}
+		if got, err := Predict(test.hash, zero, digest); err != nil {
+			t.Errorf("%v digest: %v", a, err)
+		} else if hex.EncodeToString(got) != test.digest {
+			t.Errorf("%v digest: Predict() = %x, want %s", a, got, test.digest)
+		}
+		// An event without a digest for the bank leaves it unchanged.
+		if got, err := Predict(test.hash, zero, Event{}); err != nil || !bytes.Equal(got, zero) {
+			t.Errorf("%v no digest: Predict() = %x, %v, want %x", a, got, err, zero)
+		}
+	}
+}
+
+func TestSnapshotPredict(t *testing.T) {
+	s := filledSnapshot(Layout{Count: 17, SizeOfSelect: 3}, 0, tpm2.AlgSHA1, tpm2.AlgSHA256, alg.AlgSM3_256)
+	predicted, err := s.Predict(16, Event{Data: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, test := range predictTests {
+		bank := predicted.Banks[i]
+		if got := hex.EncodeToString(bank.PCRs[16]); got != test.event {
+			t.Errorf("%s PCR 16 = %s, want %s", alg.Name(bank.Hash), got, test.event)
+		}
+		if !bytes.Equal(bank.PCRs[15], s.Banks[i].PCRs[15]) {
+			t.Errorf("%s PCR 15 changed to %x", alg.Name(bank.Hash), bank.PCRs[15])
+		}
+	}
+	if got := s.Banks[0].PCRs[16]; !bytes.Equal(got, make([]byte, 20)) {
+		t.Errorf("Predict() changed the snapshot's SHA-1 PCR 16 to %x", got)
+	}
+}
+
+func TestPredictDigestSize(t *testing.T) {
+	short := Event{Digests: map[tpm2.Algorithm][]byte{tpm2.AlgSHA256: make([]byte, 20)}}
+	if _, err := Predict(tpm2.AlgSHA256, make([]byte, 32), short); err == nil {
+		t.Errorf("Predict() with a 20 byte SHA-256 digest succeeded, want an error")
+	}
+	if _, err := ExtendDigest(tpm2.AlgSHA256, make([]byte, 20), make([]byte, 32)); err == nil {
+		t.Errorf("ExtendDigest() of a 20 byte SHA-256 PCR value succeeded, want an error")
+	}
+}
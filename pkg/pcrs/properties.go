@@ -0,0 +1,147 @@
+package pcrs
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chrisfenner/tpm-top/pkg/caps"
+)
+
+// The PCR property tags of TPM_CAP_PCR_PROPERTIES (TPM_PT_PCR).
+const (
+	ptPCRSave = 0x00
+	// ptPCRExtendL0 and ptPCRResetL0 are followed by the tags for the other
+	// localities, alternating between extend and reset.
+	ptPCRExtendL0    = 0x01
+	ptPCRResetL0     = 0x02
+	ptPCRNoIncrement = 0x11
+	ptPCRDRTMReset   = 0x12
+	ptPCRPolicy      = 0x13
+	ptPCRAuth        = 0x14
+)
+
+// numLocalities is the number of localities with their own PCR properties.
+const numLocalities = 5
+
+// Localities is a set of localities, with bit n set for locality n.
+type Localities uint8
+
+// allLocalities holds every locality from 0 to 4.
+const allLocalities Localities = 1<<numLocalities - 1
+
+// Has reports whether the locality is in the set.
+func (l Localities) Has(locality int) bool {
+	return l&(1<<uint(locality)) != 0
+}
+
+// String formats the localities as a list or range, e.g. "L4" or "L2-4".
+func (l Localities) String() string {
+	list := make([]int, 0)
+	for i := 0; i < numLocalities; i++ {
+		if l.Has(i) {
+			list = append(list, i)
+		}
+	}
+	if len(list) == 0 {
+		return "none"
+	}
+	first, last := list[0], list[len(list)-1]
+	if len(list) > 2 && last-first+1 == len(list) {
+		return fmt.Sprintf("L%d-%d", first, last)
+	}
+	names := make([]string, 0, len(list))
+	for _, locality := range list {
+		names = append(names, fmt.Sprint(locality))
+	}
+	return "L" + strings.Join(names, ",")
+}
+
+// Properties describes how a PCR behaves (TPM_CAP_PCR_PROPERTIES).
+type Properties struct {
+	// Saved is set if the PCR is saved by TPM2_Shutdown(TPM_SU_STATE).
+	Saved bool
+	// Extend are the localities that may extend the PCR.
+	Extend Localities
+	// Reset are the localities that may reset the PCR.
+	Reset Localities
+	// NoIncrement is set if changing the PCR does not increment the PCR
+	// update counter.
+	NoIncrement bool
+	// DRTMReset is set if the PCR is reset by a dynamic root of trust
+	// (H-CRTM) event.
+	DRTMReset bool
+	// Policy is set if the PCR may be given an authorization policy.
+	Policy bool
+	// Auth is set if the PCR may be given an authorization value.
+	Auth bool
+}
+
+// Annotation summarizes the ways the PCR differs from an ordinary static PCR,
+// e.g. "reset L4, drtm", or returns "" if it does not.
+func (p Properties) Annotation() string {
+	notes := make([]string, 0)
+	if p.Reset != 0 {
+		if p.Reset == allLocalities {
+			notes = append(notes, "reset")
+		} else {
+			notes = append(notes, "reset "+p.Reset.String())
+		}
+	}
+	if p.Extend != allLocalities {
+		notes = append(notes, "ext "+p.Extend.String())
+	}
+	if p.DRTMReset {
+		notes = append(notes, "drtm")
+	}
+	if !p.Saved {
+		notes = append(notes, "no-save")
+	}
+	if p.NoIncrement {
+		notes = append(notes, "no-incr")
+	}
+	if p.Policy {
+		notes = append(notes, "policy")
+	}
+	if p.Auth {
+		notes = append(notes, "auth")
+	}
+	return strings.Join(notes, ", ")
+}
+
+// GetProperties queries the properties of each of the TPM's PCRs, indexed by
+// PCR.
+func GetProperties(tpm io.ReadWriter, l Layout) ([]Properties, error) {
+	tagged, err := caps.PCRProperties(tpm)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Properties, l.Count)
+	for _, prop := range tagged {
+		for _, pcr := range prop.PCRs {
+			if pcr >= len(result) {
+				continue
+			}
+			p := &result[pcr]
+			switch tag := prop.Tag; {
+			case tag == ptPCRSave:
+				p.Saved = true
+			case tag >= ptPCRExtendL0 && tag < ptPCRExtendL0+2*numLocalities:
+				if (tag-ptPCRExtendL0)%2 == 0 {
+					p.Extend |= 1 << ((tag - ptPCRExtendL0) / 2)
+				} else {
+					p.Reset |= 1 << ((tag - ptPCRResetL0) / 2)
+				}
+			case tag == ptPCRNoIncrement:
+				p.NoIncrement = true
+			case tag == ptPCRDRTMReset:
+				p.DRTMReset = true
+			case tag == ptPCRPolicy:
+				p.Policy = true
+			case tag == ptPCRAuth:
+				p.Auth = true
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,197 @@
+package pcrs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/chrisfenner/tpm-top/pkg/rc"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+const (
+	// maxReadDigests is the most digests TPM2_PCR_Read returns at a time, the
+	// capacity of a TPML_DIGEST.
+	maxReadDigests = 8
+	// defaultMaxResponse is the response size limit to assume if the TPM does
+	// not report TPM_PT_MAX_RESPONSE_SIZE.
+	defaultMaxResponse = 4096
+	// maxDigestSize is the digest size to assume for unknown hash algorithms.
+	maxDigestSize = 64
+)
+
+// ptMaxResponseSize is TPM_PT_MAX_RESPONSE_SIZE, which go-tpm does not define.
+const ptMaxResponseSize tpm2.TPMProp = 0x11f
+
+// values holds PCR values by bank and PCR index.
+type values map[tpm2.Algorithm]map[int][]byte
+
+func (v values) set(hash tpm2.Algorithm, pcr int, value []byte) {
+	if v[hash] == nil {
+		v[hash] = make(map[int][]byte)
+	}
+	v[hash][pcr] = value
+}
+
+// readOnce runs a single TPM2_PCR_Read. It returns the values of the PCRs the
+// TPM actually read, as listed in the output selection, which may be fewer
+// than the ones selected, and the PCR update counter.
+func readOnce(tpm io.ReadWriter, l Layout, sels []tpm2.PCRSelection) (values, uint32, error) {
+	selection, err := l.EncodeSelection(sels...)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, code, err := tpmutil.RunCommand(tpm, tpm2.TagNoSessions, tpm2.CmdPCRRead, tpmutil.RawBytes(selection))
+	if err != nil {
+		return nil, 0, err
+	}
+	if code != tpmutil.RCSuccess {
+		return nil, 0, rc.MakeCommandError(int(code), tpm2.CmdPCRRead)
+	}
+	buf := bytes.NewBuffer(resp)
+	var updateCounter uint32
+	if err := tpmutil.UnpackBuf(buf, &updateCounter); err != nil {
+		return nil, 0, fmt.Errorf("could not decode TPM2_PCR_Read response: %w", err)
+	}
+	out, err := decodeSelection(buf)
+	if err != nil {
+		return nil, 0, err
+	}
+	var digestCount uint32
+	if err := tpmutil.UnpackBuf(buf, &digestCount); err != nil {
+		return nil, 0, fmt.Errorf("could not decode TPML_DIGEST: %w", err)
+	}
+	result := make(values)
+	read := 0
+	for _, s := range out {
+		for _, pcr := range sortedPCRs(s) {
+			var digest tpmutil.U16Bytes
+			if err := tpmutil.UnpackBuf(buf, &digest); err != nil {
+				return nil, 0, fmt.Errorf("could not decode TPML_DIGEST: %w", err)
+			}
+			result.set(s.Hash, pcr, digest)
+			read++
+		}
+	}
+	if read != int(digestCount) {
+		return nil, 0, fmt.Errorf("TPM returned %d digests for %d PCRs", digestCount, read)
+	}
+	return result, updateCounter, nil
+}
+
+// digestSize returns the size of the digests in a bank.
+func digestSize(hash tpm2.Algorithm) int {
+	if a, ok := alg.ByID(hash); ok && a.IsHash() {
+		return a.DigestSize
+	}
+	return maxDigestSize
+}
+
+// nextBatch picks the PCRs for the next TPM2_PCR_Read from the remaining ones,
+// packing as many banks into the selection as fit in one response.
+func nextBatch(l Layout, remaining []tpm2.PCRSelection, maxResponse int) []tpm2.PCRSelection {
+	// Response header, pcrUpdateCounter, and the counts of the
+	// TPML_PCR_SELECTION and TPML_DIGEST.
+	budget := maxResponse - 10 - 4 - 4 - 4
+	digests := 0
+	batch := make([]tpm2.PCRSelection, 0)
+	for _, sel := range remaining {
+		if len(sel.PCRs) == 0 {
+			continue
+		}
+		// The bank's TPMS_PCR_SELECTION: hash, sizeofSelect and pcrSelect.
+		budget -= 2 + 1 + l.SizeOfSelect
+		size := 2 + digestSize(sel.Hash)
+		picked := tpm2.PCRSelection{Hash: sel.Hash}
+		for _, pcr := range sel.PCRs {
+			if digests == maxReadDigests || budget < size {
+				break
+			}
+			picked.PCRs = append(picked.PCRs, pcr)
+			budget -= size
+			digests++
+		}
+		if len(picked.PCRs) == 0 {
+			break
+		}
+		batch = append(batch, picked)
+	}
+	return batch
+}
+
+// readAll reads all the selected PCRs in as few TPM2_PCR_Read commands as
+// possible, and records the PCR update counter of every read in check. PCRs
+// the TPM does not read because they are not allocated are left out.
+func readAll(tpm io.ReadWriter, l Layout, sels []tpm2.PCRSelection, maxResponse int, check *counterCheck) (values, error) {
+	remaining := make([]tpm2.PCRSelection, 0, len(sels))
+	for _, sel := range sels {
+		remaining = append(remaining, tpm2.PCRSelection{Hash: sel.Hash, PCRs: sortedPCRs(sel)})
+	}
+	result := make(values)
+	for {
+		batch := nextBatch(l, remaining, maxResponse)
+		if len(batch) == 0 {
+			return result, nil
+		}
+		read, counter, err := readOnce(tpm, l, batch)
+		if err != nil {
+			return nil, err
+		}
+		check.add(counter)
+		count := 0
+		for hash, pcrs := range read {
+			for pcr, value := range pcrs {
+				result.set(hash, pcr, value)
+				count++
+			}
+		}
+		// The TPM stops early only when the digest list is full. If it
+		// was not, the PCRs it left out are not allocated.
+		full := count == maxReadDigests
+		for i, sel := range remaining {
+			left := make([]int, 0, len(sel.PCRs))
+			for _, pcr := range sel.PCRs {
+				if _, ok := read[sel.Hash][pcr]; ok {
+					continue
+				}
+				if !full && inBatch(batch, sel.Hash, pcr) {
+					continue
+				}
+				left = append(left, pcr)
+			}
+			remaining[i].PCRs = left
+		}
+	}
+}
+
+// inBatch reports whether the PCR was selected in the batch.
+func inBatch(batch []tpm2.PCRSelection, hash tpm2.Algorithm, pcr int) bool {
+	for _, sel := range batch {
+		if sel.Hash != hash {
+			continue
+		}
+		for _, p := range sel.PCRs {
+			if p == pcr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Read reads all the selected PCRs in a bank, as many at a time as the TPM
+// allows. The PCRs may change between reads; use TakeSnapshot for a
+// consistent view.
+func Read(tpm io.ReadWriter, l Layout, sel tpm2.PCRSelection) (map[int][]byte, error) {
+	read, err := readAll(tpm, l, []tpm2.PCRSelection{sel}, defaultMaxResponse, &counterCheck{})
+	if err != nil {
+		return nil, err
+	}
+	result := read[sel.Hash]
+	if result == nil {
+		result = make(map[int][]byte)
+	}
+	return result, nil
+}
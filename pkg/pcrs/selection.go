@@ -0,0 +1,121 @@
+package pcrs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// maxIndex is the highest PCR index accepted. It is well within what a
+// TPMS_PCR_SELECTION can select with its 8-bit sizeofSelect, and keeps ranges
+// from running away before the layout is checked.
+const maxIndex = 255
+
+// ParseSelection parses a PCR selection in the form tpm2-tools uses, e.g.
+// "sha1:0,1,2+sha256:0-7". The banks may also be separated by commas, as in
+// "SHA256: 0-23, SHA1: 0-7". Each PCR may be a single index or a range. The
+// banks are kept in the given order.
+func ParseSelection(s string) ([]tpm2.PCRSelection, error) {
+	result := make([]tpm2.PCRSelection, 0)
+	for _, part := range splitBanks(s) {
+		colon := strings.Index(part, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("invalid PCR selection %q: expected <alg>:<pcrs>", part)
+		}
+		a, err := alg.LookupHash(strings.TrimSpace(part[:colon]))
+		if err != nil {
+			return nil, err
+		}
+		for _, sel := range result {
+			if sel.Hash == a.ID {
+				return nil, fmt.Errorf("invalid PCR selection %q: %v is selected twice", s, a)
+			}
+		}
+		pcrs, err := parseIndices(part[colon+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid PCR selection %q: %w", part, err)
+		}
+		result = append(result, tpm2.PCRSelection{Hash: a.ID, PCRs: pcrs})
+	}
+	return result, nil
+}
+
+// splitBanks splits a PCR selection into the selections of each bank, at each
+// "+", or at each comma followed by a bank name.
+func splitBanks(s string) []string {
+	result := make([]string, 0)
+	for _, part := range strings.Split(s, "+") {
+		start := len(result)
+		for _, item := range strings.Split(part, ",") {
+			if len(result) == start || strings.Contains(item, ":") {
+				result = append(result, item)
+				continue
+			}
+			result[len(result)-1] += "," + item
+		}
+	}
+	return result
+}
+
+// FormatIndices formats PCR indices in ascending order, with runs as ranges,
+// e.g. "0-7,16", or "none" if there are none.
+func FormatIndices(pcrs []int) string {
+	sorted := sortedPCRs(tpm2.PCRSelection{PCRs: pcrs})
+	if len(sorted) == 0 {
+		return "none"
+	}
+	ranges := make([]string, 0)
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, strconv.Itoa(sorted[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
+
+// parseIndices parses a comma-separated list of PCR indices and ranges like
+// "0-7", in ascending order without duplicates.
+func parseIndices(s string) ([]int, error) {
+	selected := make(map[int]bool)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		first, last := item, item
+		if dash := strings.Index(item, "-"); dash >= 0 {
+			first, last = strings.TrimSpace(item[:dash]), strings.TrimSpace(item[dash+1:])
+		}
+		lo, err := strconv.Atoi(first)
+		if err != nil || lo < 0 || lo > maxIndex {
+			return nil, fmt.Errorf("invalid PCR index %q", first)
+		}
+		hi, err := strconv.Atoi(last)
+		if err != nil || hi < lo || hi > maxIndex {
+			return nil, fmt.Errorf("invalid PCR range %q", item)
+		}
+		for pcr := lo; pcr <= hi; pcr++ {
+			selected[pcr] = true
+		}
+	}
+	return sortedPCRs(tpm2.PCRSelection{PCRs: keys(selected)}), nil
+}
+
+// keys returns the keys of a set of PCR indices.
+func keys(set map[int]bool) []int {
+	result := make([]int, 0, len(set))
+	for pcr := range set {
+		result = append(result, pcr)
+	}
+	return result
+}
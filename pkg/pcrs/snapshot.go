@@ -0,0 +1,202 @@
+package pcrs
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/chrisfenner/tpm-top/pkg/caps"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// maxSnapshotAttempts is the number of times a snapshot reads the PCRs before
+// giving up because they keep changing.
+const maxSnapshotAttempts = 5
+
+// Bank holds the values of the allocated PCRs in a PCR bank.
+type Bank struct {
+	// Hash is the hash algorithm of the bank.
+	Hash tpm2.Algorithm
+	// PCRs maps the index of each allocated PCR to its value.
+	PCRs map[int][]byte
+}
+
+// Snapshot is a consistent view of all the PCR banks at one point in time.
+type Snapshot struct {
+	// Time is when the snapshot was taken.
+	Time time.Time
+	// UpdateCounter is the TPM's pcrUpdateCounter when the snapshot was taken.
+	UpdateCounter uint32
+	// Banks are the active PCR banks, in the order the TPM reports them.
+	Banks []Bank
+}
+
+// Bank returns the bank with the given hash algorithm, or nil if the snapshot
+// has no such bank.
+func (s *Snapshot) Bank(hash tpm2.Algorithm) *Bank {
+	for i := range s.Banks {
+		if s.Banks[i].Hash == hash {
+			return &s.Banks[i]
+		}
+	}
+	return nil
+}
+
+// counterCheck tracks the PCR update counter across several TPM2_PCR_Read
+// calls.
+type counterCheck struct {
+	// first is the counter returned by the first read.
+	first uint32
+	// reads is the number of reads so far.
+	reads int
+	// changed is set if any read returned a different counter than the first.
+	changed bool
+}
+
+func (c *counterCheck) add(counter uint32) {
+	if c.reads == 0 {
+		c.first = counter
+	} else if counter != c.first {
+		c.changed = true
+	}
+	c.reads++
+}
+
+// getMaxResponse queries the TPM's response size limit.
+func getMaxResponse(tpm io.ReadWriter) int {
+	size, err := caps.Property(tpm, ptMaxResponseSize)
+	if err != nil || size == 0 {
+		return defaultMaxResponse
+	}
+	return int(size)
+}
+
+// snapshot reads every allocated PCR in the given banks, until the PCR update
+// counter is the same for all the reads.
+func snapshot(tpm io.ReadWriter, l Layout, banks []tpm2.PCRSelection, maxResponse int) (*Snapshot, error) {
+	for attempt := 0; attempt < maxSnapshotAttempts; attempt++ {
+		check := counterCheck{}
+		read, err := readAll(tpm, l, banks, maxResponse, &check)
+		if err != nil {
+			return nil, err
+		}
+		if check.changed {
+			continue
+		}
+		snap := Snapshot{
+			Time:          time.Now(),
+			UpdateCounter: check.first,
+			Banks:         make([]Bank, 0, len(banks)),
+		}
+		for _, sel := range banks {
+			if pcrs := read[sel.Hash]; len(pcrs) != 0 {
+				snap.Banks = append(snap.Banks, Bank{
+					Hash: sel.Hash,
+					PCRs: pcrs,
+				})
+			}
+		}
+		return &snap, nil
+	}
+	return nil, fmt.Errorf("PCRs changed during each of %d attempts to read them", maxSnapshotAttempts)
+}
+
+// TakeSnapshot reads every allocated PCR in every active bank. If any PCR is
+// extended while they are read, as shown by a change in the PCR update
+// counter, it reads them all again.
+func TakeSnapshot(tpm io.ReadWriter, l Layout) (*Snapshot, error) {
+	banks, err := GetBanks(tpm)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot(tpm, l, banks, getMaxResponse(tpm))
+}
+
+// Reader takes PCR snapshots from a TPM. It caches the PCR layout, bank
+// allocation and PCR properties, which only change when the TPM is reset, so that each snapshot
+// only costs the TPM2_PCR_Read commands themselves.
+type Reader struct {
+	tpm io.ReadWriter
+	// cached is set when the fields below are valid.
+	cached      bool
+	layout      Layout
+	banks       []tpm2.PCRSelection
+	properties  []Properties
+	maxResponse int
+	// lastCounter is the PCR update counter of the last snapshot.
+	lastCounter uint32
+}
+
+// NewReader creates a Reader for the TPM.
+func NewReader(tpm io.ReadWriter) *Reader {
+	return &Reader{
+		tpm: tpm,
+	}
+}
+
+// Invalidate forgets the cached PCR layout and bank allocation, e.g. after the
+// TPM was reset.
+func (r *Reader) Invalidate() {
+	r.cached = false
+}
+
+func (r *Reader) fill() error {
+	if r.cached {
+		return nil
+	}
+	layout, err := GetLayout(r.tpm)
+	if err != nil {
+		return err
+	}
+	banks, err := GetBanks(r.tpm)
+	if err != nil {
+		return err
+	}
+	properties, err := GetProperties(r.tpm, layout)
+	if err != nil {
+		return err
+	}
+	r.layout = layout
+	r.banks = banks
+	r.properties = properties
+	r.maxResponse = getMaxResponse(r.tpm)
+	r.cached = true
+	return nil
+}
+
+// Layout returns the PCR layout of the TPM.
+func (r *Reader) Layout() (Layout, error) {
+	if err := r.fill(); err != nil {
+		return Layout{}, err
+	}
+	return r.layout, nil
+}
+
+// Properties returns the properties of each PCR, indexed by PCR.
+func (r *Reader) Properties() ([]Properties, error) {
+	if err := r.fill(); err != nil {
+		return nil, err
+	}
+	return r.properties, nil
+}
+
+// Snapshot reads every allocated PCR in every active bank, like TakeSnapshot.
+func (r *Reader) Snapshot() (*Snapshot, error) {
+	if err := r.fill(); err != nil {
+		return nil, err
+	}
+	snap, err := snapshot(r.tpm, r.layout, r.banks, r.maxResponse)
+	if err != nil {
+		r.Invalidate()
+		return nil, err
+	}
+	// The PCR update counter only goes down when the TPM is reset, which is
+	// also when a new PCR allocation takes effect.
+	if snap.UpdateCounter < r.lastCounter {
+		r.Invalidate()
+		r.lastCounter = 0
+		return r.Snapshot()
+	}
+	r.lastCounter = snap.UpdateCounter
+	return snap, nil
+}
@@ -0,0 +1,126 @@
+package pcrs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// yamlNames are the bank names tpm2-tools uses, where they differ from the
+// algorithm's display name.
+var yamlNames = map[tpm2.Algorithm]string{
+	tpm2.AlgSHA256:   "sha256",
+	tpm2.AlgSHA384:   "sha384",
+	tpm2.AlgSHA512:   "sha512",
+	alg.AlgSM3_256:   "sm3_256",
+	tpm2.AlgSHA3_256: "sha3_256",
+	tpm2.AlgSHA3_384: "sha3_384",
+	tpm2.AlgSHA3_512: "sha3_512",
+}
+
+// yamlName returns the name tpm2-tools uses for a bank.
+func yamlName(hash tpm2.Algorithm) string {
+	if name, ok := yamlNames[hash]; ok {
+		return name
+	}
+	return hashName(hash)
+}
+
+// WriteYAML writes the PCR values in the YAML layout of tpm2_pcrread from
+// tpm2-tools.
+func (s *Snapshot) WriteYAML(w io.Writer) error {
+	var buf bytes.Buffer
+	for i := range s.Banks {
+		bank := &s.Banks[i]
+		fmt.Fprintf(&buf, "  %s:\n", yamlName(bank.Hash))
+		for _, pcr := range bank.Indices() {
+			fmt.Fprintf(&buf, "    %-2d: 0x%X\n", pcr, bank.PCRs[pcr])
+		}
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// ParseYAML reads PCR values in the YAML layout of tpm2_pcrread. Comments and
+// keys that do not name a hash algorithm (e.g., a top-level "pcrs:") are
+// ignored. If there are several YAML documents, like the output of
+// "tpm-top -b", the last one with any banks is used. The snapshot has no time
+// or update counter.
+func ParseYAML(data []byte) (*Snapshot, error) {
+	result := Snapshot{
+		Banks: make([]Bank, 0),
+	}
+	// previous is the last document with any banks before this one.
+	var previous []Bank
+	var bank *Bank
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line == "---" {
+			// A new document replaces the one before, unless it is empty.
+			if len(result.Banks) != 0 {
+				previous = result.Banks
+				result.Banks = make([]Bank, 0)
+			}
+			bank = nil
+			continue
+		}
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("line %d: expected 'key: value'", lineNum)
+		}
+		key := strings.TrimSpace(line[:colon])
+		value := strings.TrimSpace(line[colon+1:])
+		if value == "" {
+			// A bank, or some other container.
+			hash, err := parseHashName(key)
+			if err != nil {
+				bank = nil
+				continue
+			}
+			if result.Bank(hash) != nil {
+				return nil, fmt.Errorf("line %d: bank %s appears twice", lineNum, key)
+			}
+			result.Banks = append(result.Banks, Bank{
+				Hash: hash,
+				PCRs: make(map[int][]byte),
+			})
+			bank = &result.Banks[len(result.Banks)-1]
+			continue
+		}
+		if bank == nil {
+			return nil, fmt.Errorf("line %d: PCR value outside of a bank", lineNum)
+		}
+		pcr, err := strconv.Atoi(key)
+		if err != nil || pcr < 0 {
+			return nil, fmt.Errorf("line %d: invalid PCR index %q", lineNum, key)
+		}
+		value = strings.TrimPrefix(strings.TrimPrefix(value, "0x"), "0X")
+		digest, err := hex.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid PCR value: %w", lineNum, err)
+		}
+		bank.PCRs[pcr] = digest
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(result.Banks) == 0 && previous != nil {
+		result.Banks = previous
+	}
+	return &result, nil
+}
@@ -0,0 +1,73 @@
+package pcrs
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/chrisfenner/tpm-top/pkg/alg"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// testSnapshot returns a snapshot with a SHA-1, a SHA-256 and an SM3 bank,
+// whose PCRs all have the given byte as their value.
+func testSnapshot(b byte) *Snapshot {
+	s := &Snapshot{Banks: make([]Bank, 0)}
+	for _, hash := range []tpm2.Algorithm{tpm2.AlgSHA1, tpm2.AlgSHA256, alg.AlgSM3_256} {
+		a, _ := alg.ByID(hash)
+		bank := Bank{Hash: hash, PCRs: make(map[int][]byte)}
+		for _, pcr := range []int{0, 7, 10, 23} {
+			bank.PCRs[pcr] = bytes.Repeat([]byte{b}, a.DigestSize)
+		}
+		s.Banks = append(s.Banks, bank)
+	}
+	return s
+}
+
+// yaml returns the snapshot in the YAML layout of tpm2_pcrread.
+func yaml(t *testing.T, s *Snapshot) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := s.WriteYAML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	first, second := testSnapshot(0x00), testSnapshot(0xab)
+	batch := func(docs ...[]byte) []byte {
+		var buf bytes.Buffer
+		for _, doc := range docs {
+			buf.WriteString("---\n# 2020-01-01T00:00:00Z, update counter 1\n")
+			buf.Write(doc)
+		}
+		return buf.Bytes()
+	}
+	for _, test := range []struct {
+		name string
+		data []byte
+		want *Snapshot
+	}{
+		{"tpm2_pcrread", append([]byte("pcrs:\n"), yaml(t, first)...), first},
+		{"one document", batch(yaml(t, first)), first},
+		{"two documents", batch(yaml(t, first), yaml(t, second)), second},
+		{"empty last document", append(batch(yaml(t, first), yaml(t, second)), "---\n"...), second},
+	} {
+		got, err := ParseYAML(test.data)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got.Banks, test.want.Banks) {
+			t.Errorf("%s: ParseYAML() = %v, want %v", test.name, got.Banks, test.want.Banks)
+		}
+	}
+}
+
+func TestParseYAMLDuplicateBank(t *testing.T) {
+	doc := yaml(t, testSnapshot(0))
+	if _, err := ParseYAML(append(doc, doc...)); err == nil {
+		t.Errorf("ParseYAML() of a document with each bank twice succeeded, want an error")
+	}
+}
@@ -0,0 +1,51 @@
+package pt
+
+//go:generate go run github.com/chrisfenner/tpm-top/internal/specgen -in ../../spec/tpm2.json -table pt -out tables_gen.go
+
+import (
+	"strings"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+// Property describes a TPM 2.0 property tag (TPM_PT), as listed in TPM 2.0 Part 2.
+type Property struct {
+	// Tag is the property tag.
+	Tag tpm2.TPMProp
+	// Name is the symbol name in the TPM specification.
+	Name string
+	// Description is the description in the TPM specification.
+	Description string
+}
+
+// Lookup finds the property with the given tag.
+func Lookup(tag tpm2.TPMProp) (Property, bool) {
+	for _, prop := range properties {
+		if prop.Tag == tag {
+			return prop, true
+		}
+	}
+	return Property{}, false
+}
+
+// LookupName finds the property with the given name. The match is
+// case-insensitive and the "TPM_PT_" prefix is optional.
+func LookupName(name string) (Property, bool) {
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "TPM_PT_") {
+		name = "TPM_PT_" + name
+	}
+	for _, prop := range properties {
+		if prop.Name == name {
+			return prop, true
+		}
+	}
+	return Property{}, false
+}
+
+// Properties returns all the known properties, ordered by tag.
+func Properties() []Property {
+	result := make([]Property, len(properties))
+	copy(result, properties)
+	return result
+}
@@ -0,0 +1,14 @@
+package pt
+
+import "testing"
+
+func TestPropertiesRoundTrip(t *testing.T) {
+	for _, prop := range Properties() {
+		if found, ok := Lookup(prop.Tag); !ok || found != prop {
+			t.Errorf("Lookup(0x%x) = %+v, want %+v", prop.Tag, found, prop)
+		}
+		if found, ok := LookupName(prop.Name); !ok || found != prop {
+			t.Errorf("LookupName(%s) = %+v, want %+v", prop.Name, found, prop)
+		}
+	}
+}
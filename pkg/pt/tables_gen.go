@@ -0,0 +1,76 @@
+// Code generated by specgen from spec/tpm2.json. DO NOT EDIT.
+
+package pt
+
+var (
+	// properties is ordered by property tag.
+	properties = []Property{
+		{0x100, "TPM_PT_FAMILY_INDICATOR", "a 4-octet character string containing the TPM Family value (TPM_SPEC_FAMILY)"},
+		{0x101, "TPM_PT_LEVEL", "the level of the specification"},
+		{0x102, "TPM_PT_REVISION", "the specification Revision times 100"},
+		{0x103, "TPM_PT_DAY_OF_YEAR", "the specification day of year using TCG calendar"},
+		{0x104, "TPM_PT_YEAR", "the specification year using the CE"},
+		{0x105, "TPM_PT_MANUFACTURER", "the vendor ID unique to each TPM manufacturer"},
+		{0x106, "TPM_PT_VENDOR_STRING_1", "the first four characters of the vendor ID string"},
+		{0x107, "TPM_PT_VENDOR_STRING_2", "the second four characters of the vendor ID string"},
+		{0x108, "TPM_PT_VENDOR_STRING_3", "the third four characters of the vendor ID string"},
+		{0x109, "TPM_PT_VENDOR_STRING_4", "the fourth four characters of the vendor ID string"},
+		{0x10A, "TPM_PT_VENDOR_TPM_TYPE", "vendor-defined value indicating the TPM model"},
+		{0x10B, "TPM_PT_FIRMWARE_VERSION_1", "the most-significant 32 bits of a TPM vendor-specific value indicating the version number of the firmware"},
+		{0x10C, "TPM_PT_FIRMWARE_VERSION_2", "the least-significant 32 bits of a TPM vendor-specific value indicating the version number of the firmware"},
+		{0x10D, "TPM_PT_INPUT_BUFFER", "the maximum size of a parameter (typically, a TPM2B_MAX_BUFFER)"},
+		{0x10E, "TPM_PT_HR_TRANSIENT_MIN", "the minimum number of transient objects that can be held in TPM RAM"},
+		{0x10F, "TPM_PT_HR_PERSISTENT_MIN", "the minimum number of persistent objects that can be held in TPM NV memory"},
+		{0x110, "TPM_PT_HR_LOADED_MIN", "the minimum number of authorization sessions that can be held in TPM RAM"},
+		{0x111, "TPM_PT_ACTIVE_SESSIONS_MAX", "the number of authorization sessions that may be active at a time"},
+		{0x112, "TPM_PT_PCR_COUNT", "the number of PCR implemented"},
+		{0x113, "TPM_PT_PCR_SELECT_MIN", "the minimum number of octets in a TPMS_PCR_SELECT.sizeOfSelect"},
+		{0x114, "TPM_PT_CONTEXT_GAP_MAX", "the maximum allowed difference (unsigned) between the contextID values of two saved session contexts"},
+		{0x116, "TPM_PT_NV_COUNTERS_MAX", "the maximum number of NV Indexes that are allowed to have the TPM_NT_COUNTER attribute"},
+		{0x117, "TPM_PT_NV_INDEX_MAX", "the maximum size of an NV Index data area"},
+		{0x118, "TPM_PT_MEMORY", "a TPMA_MEMORY indicating the memory management method for the TPM"},
+		{0x119, "TPM_PT_CLOCK_UPDATE", "interval, in milliseconds, between updates to the copy of TPMS_CLOCK_INFO.clock in NV"},
+		{0x11A, "TPM_PT_CONTEXT_HASH", "the algorithm used for the integrity HMAC on saved contexts and for hashing the fuData of TPM2_FirmwareRead()"},
+		{0x11B, "TPM_PT_CONTEXT_SYM", "TPM_ALG_ID, the algorithm used for encryption of saved contexts"},
+		{0x11C, "TPM_PT_CONTEXT_SYM_SIZE", "TPM_KEY_BITS, the size of the key used for encryption of saved contexts"},
+		{0x11D, "TPM_PT_ORDERLY_COUNT", "the modulus - 1 of the count for NV update of an orderly counter"},
+		{0x11E, "TPM_PT_MAX_COMMAND_SIZE", "the maximum value for commandSize in a command"},
+		{0x11F, "TPM_PT_MAX_RESPONSE_SIZE", "the maximum value for responseSize in a response"},
+		{0x120, "TPM_PT_MAX_DIGEST", "the maximum size of a digest that can be produced by the TPM"},
+		{0x121, "TPM_PT_MAX_OBJECT_CONTEXT", "the maximum size of an object context that will be returned by TPM2_ContextSave"},
+		{0x122, "TPM_PT_MAX_SESSION_CONTEXT", "the maximum size of a session context that will be returned by TPM2_ContextSave"},
+		{0x123, "TPM_PT_PS_FAMILY_INDICATOR", "platform-specific family (a TPM_PS value)"},
+		{0x124, "TPM_PT_PS_LEVEL", "the level of the platform-specific specification"},
+		{0x125, "TPM_PT_PS_REVISION", "a platform specific value"},
+		{0x126, "TPM_PT_PS_DAY_OF_YEAR", "the platform-specific TPM specification day of year using TCG calendar"},
+		{0x127, "TPM_PT_PS_YEAR", "the platform-specific TPM specification year using the CE"},
+		{0x128, "TPM_PT_SPLIT_MAX", "the number of split signing operations supported by the TPM"},
+		{0x129, "TPM_PT_TOTAL_COMMANDS", "total number of commands implemented in the TPM"},
+		{0x12A, "TPM_PT_LIBRARY_COMMANDS", "number of commands from the TPM library that are implemented"},
+		{0x12B, "TPM_PT_VENDOR_COMMANDS", "number of vendor commands that are implemented"},
+		{0x12C, "TPM_PT_NV_BUFFER_MAX", "the maximum data size in one NV write, NV read, NV extend, or NV certify command"},
+		{0x12D, "TPM_PT_MODES", "a TPMA_MODES value, indicating that the TPM is designed for these modes"},
+		{0x12E, "TPM_PT_MAX_CAP_BUFFER", "the maximum size of a TPMS_CAPABILITY_DATA structure returned in TPM2_GetCapability()"},
+		{0x200, "TPM_PT_PERMANENT", "TPMA_PERMANENT"},
+		{0x201, "TPM_PT_STARTUP_CLEAR", "TPMA_STARTUP_CLEAR"},
+		{0x202, "TPM_PT_HR_NV_INDEX", "the number of NV Indexes currently defined"},
+		{0x203, "TPM_PT_HR_LOADED", "the number of authorization sessions currently loaded into TPM RAM"},
+		{0x204, "TPM_PT_HR_LOADED_AVAIL", "the number of additional authorization sessions, of any type, that could be loaded into TPM RAM"},
+		{0x205, "TPM_PT_HR_ACTIVE", "the number of active authorization sessions currently being tracked by the TPM"},
+		{0x206, "TPM_PT_HR_ACTIVE_AVAIL", "the number of additional authorization sessions, of any type, that could be created"},
+		{0x207, "TPM_PT_HR_TRANSIENT_AVAIL", "estimate of the number of additional transient objects that could be loaded into TPM RAM"},
+		{0x208, "TPM_PT_HR_PERSISTENT", "the number of persistent objects currently loaded into TPM NV memory"},
+		{0x209, "TPM_PT_HR_PERSISTENT_AVAIL", "the number of additional persistent objects that could be loaded into NV memory"},
+		{0x20A, "TPM_PT_NV_COUNTERS", "the number of defined NV Indexes that have NV the TPM_NT_COUNTER attribute"},
+		{0x20B, "TPM_PT_NV_COUNTERS_AVAIL", "the number of additional NV Indexes that can be defined with their TPM_NT of TPM_NV_COUNTER"},
+		{0x20C, "TPM_PT_ALGORITHM_SET", "code that limits the algorithms that may be used with the TPM"},
+		{0x20D, "TPM_PT_LOADED_CURVES", "the number of loaded ECC curves"},
+		{0x20E, "TPM_PT_LOCKOUT_COUNTER", "the current value of the lockout counter (failedTries)"},
+		{0x20F, "TPM_PT_MAX_AUTH_FAIL", "the number of authorization failures before DA lockout is invoked"},
+		{0x210, "TPM_PT_LOCKOUT_INTERVAL", "the number of seconds before the value reported by TPM_PT_LOCKOUT_COUNTER is decremented"},
+		{0x211, "TPM_PT_LOCKOUT_RECOVERY", "the number of seconds after a lockoutAuth failure before use of lockoutAuth may be attempted again"},
+		{0x212, "TPM_PT_NV_WRITE_RECOVERY", "number of milliseconds before the TPM will accept another command that will modify NV"},
+		{0x213, "TPM_PT_AUDIT_COUNTER_0", "the high-order 32 bits of the command audit counter"},
+		{0x214, "TPM_PT_AUDIT_COUNTER_1", "the low-order 32 bits of the command audit counter"},
+	}
+)
@@ -0,0 +1,28 @@
+package rc
+
+import (
+	"github.com/chrisfenner/tpm-top/pkg/cc"
+)
+
+// Describe names the idx'th (counting from 1) handle, parameter or session of
+// the command. It returns an empty string if the command has no such item.
+func Describe(cmd cc.Command, r Relation, idx int) string {
+	if idx < 1 {
+		return ""
+	}
+	switch r {
+	case Handle:
+		if handles := cmd.HandleNames(); idx <= len(handles) {
+			return handles[idx-1]
+		}
+	case Parameter:
+		if idx <= len(cmd.Parameters) {
+			return cmd.Parameters[idx-1]
+		}
+	case Session:
+		if auths := cmd.AuthHandles(); idx <= len(auths) {
+			return "authorization for " + auths[idx-1]
+		}
+	}
+	return ""
+}
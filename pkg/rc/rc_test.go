@@ -0,0 +1,42 @@
+package rc
+
+import (
+	"fmt"
+	"testing"
+)
+
+// checkCode checks that the response code value decodes back to code.
+func checkCode(t *testing.T, code Code, value int, suffix string) {
+	t.Helper()
+	decoded, ok := Decode(value)
+	if !ok || decoded != code {
+		t.Errorf("Decode(0x%x) = %+v, want %+v", value, decoded, code)
+	}
+	err := MakeError(value)
+	want := fmt.Sprintf("(0x%x) %s: %s%s", value, code.Name, code.Description, suffix)
+	if err == nil || err.Error() != want {
+		t.Errorf("MakeError(0x%x) = %v, want %s", value, err, want)
+	}
+}
+
+func TestCodesRoundTrip(t *testing.T) {
+	for _, code := range Codes() {
+		checkCode(t, code, code.Value(), "")
+		if found, ok := Lookup(code.Name); !ok || found != code {
+			t.Errorf("Lookup(%s) = %+v, want %+v", code.Name, found, code)
+		}
+		if code.Format != Fmt1 {
+			continue
+		}
+		for r, max := range map[Relation]int{Handle: 7, Parameter: 15, Session: 7} {
+			for idx := 1; idx <= max; idx++ {
+				value, err := MakeFmt1(code, r, idx)
+				if err != nil {
+					t.Errorf("MakeFmt1(%s, %s, %d): %v", code.Name, r, idx, err)
+					continue
+				}
+				checkCode(t, code, value, fmt.Sprintf(" (%s %d)", r, idx))
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package rc
+
+import (
+	"strings"
+)
+
+// Decode finds the response code from the specification that rc is an
+// instance of, ignoring any handle, parameter or session number.
+func Decode(rc int) (Code, bool) {
+	var f Format
+	var number int
+	switch err := MakeError(rc).(type) {
+	case Ver1Error:
+		f, number = Ver1, err.errorCode
+	case Fmt1Error:
+		f, number = Fmt1, err.errorCode
+	case Warning:
+		f, number = Warn, err.errorCode
+	default:
+		return Code{}, false
+	}
+	details, ok := tableFor(f)[number]
+	if !ok {
+		return Code{}, false
+	}
+	return Code{
+		Format:      f,
+		Number:      number,
+		Name:        details.name,
+		Description: details.description,
+	}, true
+}
+
+// Remedy returns standard guidance for recovering from the response code, or
+// an empty string if there is none.
+func (c Code) Remedy() string {
+	switch {
+	case strings.HasPrefix(c.Name, "TPM_RC_REFERENCE_H"):
+		return "Load the object or start the session referenced by the handle, or check that it was not flushed or evicted."
+	case strings.HasPrefix(c.Name, "TPM_RC_REFERENCE_S"):
+		return "Start the session referenced by the session handle again, or check that it was not flushed by a command with continueSession clear."
+	}
+	return remedies[c.Name]
+}
+
+// Remedy returns standard guidance for recovering from the given response
+// code, or an empty string if there is none.
+func Remedy(rc int) string {
+	code, ok := Decode(rc)
+	if !ok {
+		return ""
+	}
+	return code.Remedy()
+}
+
+var (
+	// remedies holds standard remediation guidance, by response code name.
+	remedies = map[string]string{
+		// VER1
+		"TPM_RC_INITIALIZE":        "Run TPM2_Startup (e.g., `tpm-tool startup`) before sending any other command, and only run it once per power cycle.",
+		"TPM_RC_FAILURE":           "The TPM is in failure mode. Check TPM2_GetTestResult, then power cycle the TPM (e.g., `sim-start`).",
+		"TPM_RC_SEQUENCE":          "Use a handle returned by TPM2_HashSequenceStart or TPM2_HMAC_Start, and do not use it after the sequence is complete.",
+		"TPM_RC_DISABLED":          "The command is disabled, e.g., by TPM2_ClearControl. Re-enable it or use a different command.",
+		"TPM_RC_EXCLUSIVE":         "Another command ran in between the commands of an exclusive audit session. Start the audit again.",
+		"TPM_RC_AUTH_TYPE":         "Use a session type that is allowed for the handle, e.g., a policy session when the object requires a policy.",
+		"TPM_RC_AUTH_MISSING":      "Provide an authorization session for every handle of the command that requires authorization.",
+		"TPM_RC_POLICY":            "Check that the policy digest and the policy session use the same hash algorithm.",
+		"TPM_RC_PCR":               "The current PCR values do not match the digest given to TPM2_PolicyPCR. Check the expected PCR values.",
+		"TPM_RC_PCR_CHANGED":       "A PCR was extended after TPM2_PolicyPCR. Restart the policy session and retry.",
+		"TPM_RC_UPGRADE":           "The TPM is in field upgrade mode. Finish the field upgrade and power cycle the TPM.",
+		"TPM_RC_TOO_MANY_CONTEXTS": "The context counter is exhausted. Power cycle the TPM with TPM2_Startup(CLEAR).",
+		"TPM_RC_AUTH_UNAVAILABLE":  "The entity does not allow this kind of authorization, e.g., userWithAuth is clear. Use a policy session.",
+		"TPM_RC_REBOOT":            "Power cycle the TPM (e.g., `sim-start`) and run TPM2_Startup(CLEAR).",
+		"TPM_RC_COMMAND_CODE":      "The TPM does not implement the command. Check TPM_CAP_COMMANDS for the supported commands.",
+		"TPM_RC_NV_LOCKED":         "The NV index is read- or write-locked. Locks are released on TPM2_Startup(CLEAR) unless the index is locked permanently.",
+		"TPM_RC_NV_AUTHORIZATION":  "Use the authorization the NV index allows (owner, platform or the index itself) per its attributes.",
+		"TPM_RC_NV_UNINITIALIZED":  "Write the NV index before reading it.",
+		"TPM_RC_NV_SPACE":          "Undefine unused NV indices or evict unused persistent objects.",
+		"TPM_RC_NV_DEFINED":        "The NV index or persistent handle is in use. Pick a different handle or undefine/evict the existing one.",
+		"TPM_RC_BAD_CONTEXT":       "The context blob is stale or corrupted, e.g., because the TPM was reset since it was saved.",
+		"TPM_RC_CPHASH":            "Restart the policy session; it is already bound to a different cpHash.",
+		"TPM_RC_PARENT":            "Use a restricted decryption (storage) key as the parent.",
+		"TPM_RC_NEEDS_TEST":        "Run TPM2_SelfTest or TPM2_IncrementalSelfTest for the algorithm, then retry.",
+
+		// FMT1
+		"TPM_RC_ASYMMETRIC":   "Use an asymmetric algorithm the TPM implements. Check TPM_CAP_ALGS.",
+		"TPM_RC_HASH":         "Use a hash algorithm the TPM implements and the command allows. Check TPM_CAP_ALGS.",
+		"TPM_RC_HIERARCHY":    "The hierarchy is disabled. Enable it with TPM2_HierarchyControl or power cycle the TPM.",
+		"TPM_RC_KEY_SIZE":     "Use a key size the TPM implements. Check TPM_CAP_ALGS and TPM2_TestParms.",
+		"TPM_RC_AUTH_FAIL":    "The authorization value was wrong and the dictionary attack counter was incremented. Check the password before retrying to avoid a lockout.",
+		"TPM_RC_NONCE":        "Check the nonce sizes; nonceCaller must be at least 16 bytes and no larger than the session's digest.",
+		"TPM_RC_PP":           "Assert physical presence, or remove the command from the physical presence list with TPM2_PP_Commands.",
+		"TPM_RC_POLICY_FAIL":  "The policy session does not match the entity's authPolicy. Compare TPM2_PolicyGetDigest with the authPolicy.",
+		"TPM_RC_BAD_AUTH":     "The authorization value was wrong. Check the password.",
+		"TPM_RC_EXPIRED":      "The policy session or ticket has expired. Start a new policy session.",
+		"TPM_RC_INTEGRITY":    "The blob was not created by this TPM under this parent, or the parent's seed has changed.",
+		"TPM_RC_INSUFFICIENT": "The command was truncated. Check the size fields in the marshaled structures.",
+
+		// WARN
+		"TPM_RC_CONTEXT_GAP":     "Load and save (or flush) the oldest saved session.",
+		"TPM_RC_OBJECT_MEMORY":   "Flush unneeded transient objects with TPM2_FlushContext, or save them with TPM2_ContextSave.",
+		"TPM_RC_SESSION_MEMORY":  "Flush unneeded sessions with TPM2_FlushContext, or save them with TPM2_ContextSave.",
+		"TPM_RC_MEMORY":          "Flush unneeded transient objects and sessions with TPM2_FlushContext.",
+		"TPM_RC_SESSION_HANDLES": "Flush unneeded sessions with TPM2_FlushContext.",
+		"TPM_RC_OBJECT_HANDLES":  "Power cycle the TPM.",
+		"TPM_RC_LOCALITY":        "Send the command from a locality the entity allows.",
+		"TPM_RC_YIELDED":         "Retry the command.",
+		"TPM_RC_CANCELED":        "Retry the command.",
+		"TPM_RC_TESTING":         "Wait for the self tests to finish, then retry. TPM2_GetTestResult reports progress.",
+		"TPM_RC_NV_RATE":         "Wait and retry; the TPM is limiting NV writes to prevent wearout.",
+		"TPM_RC_LOCKOUT":         "Wait for the lockout interval to pass, or run TPM2_DictionaryAttackLockReset with lockout authorization.",
+		"TPM_RC_RETRY":           "Retry the command.",
+		"TPM_RC_NV_UNAVAILABLE":  "Retry the command later.",
+	}
+)
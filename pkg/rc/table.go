@@ -0,0 +1,158 @@
+package rc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Format identifies which family of TPM 2.0 response codes a code belongs to.
+type Format int
+
+const (
+	// Ver1 codes are format-zero errors defined by TPM 2.0 (RC_VER1).
+	Ver1 Format = iota
+	// Fmt1 codes are format-one errors that may refer to a handle, parameter or session (RC_FMT1).
+	Fmt1
+	// Warn codes are format-zero warnings (RC_WARN).
+	Warn
+)
+
+func (f Format) String() string {
+	switch f {
+	case Ver1:
+		return "VER1"
+	case Fmt1:
+		return "FMT1"
+	case Warn:
+		return "WARN"
+	}
+	return ""
+}
+
+const (
+	rcVer1 = 0x100
+	rcFmt1 = 0x080
+	rcWarn = 0x900
+)
+
+// Code is a single response code from the TPM specification.
+type Code struct {
+	// Format is the family of the response code.
+	Format Format
+	// Number is the error number within its family (e.g., 0x01D for TPM_RC_POLICY_FAIL).
+	Number int
+	// Name is the symbol name in the TPM specification.
+	Name string
+	// Description is the description in the TPM specification.
+	Description string
+}
+
+// Value returns the full response code value, without any handle, parameter or
+// session number.
+func (c Code) Value() int {
+	switch c.Format {
+	case Fmt1:
+		return rcFmt1 | c.Number
+	case Warn:
+		return rcWarn | c.Number
+	}
+	return rcVer1 | c.Number
+}
+
+// tableFor returns the table of response codes for the given format.
+func tableFor(f Format) map[int]rcDetails {
+	switch f {
+	case Ver1:
+		return ver1RespCodes
+	case Fmt1:
+		return fmt1RespCodes
+	case Warn:
+		return warningRespCodes
+	}
+	return nil
+}
+
+// CodesOfFormat returns all the known response codes of the given format,
+// ordered by number.
+func CodesOfFormat(f Format) []Code {
+	table := tableFor(f)
+	result := make([]Code, 0, len(table))
+	for number, details := range table {
+		result = append(result, Code{
+			Format:      f,
+			Number:      number,
+			Name:        details.name,
+			Description: details.description,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Number < result[j].Number
+	})
+	return result
+}
+
+// Codes returns all the known response codes, grouped by format and ordered by
+// number.
+func Codes() []Code {
+	result := make([]Code, 0)
+	for _, f := range []Format{Ver1, Fmt1, Warn} {
+		result = append(result, CodesOfFormat(f)...)
+	}
+	return result
+}
+
+// Lookup finds the response code with the given name. The match is
+// case-insensitive and the "TPM_RC_" prefix is optional.
+func Lookup(name string) (Code, bool) {
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "TPM_RC_") {
+		name = "TPM_RC_" + name
+	}
+	for _, code := range Codes() {
+		if code.Name == name {
+			return code, true
+		}
+	}
+	return Code{}, false
+}
+
+// Search returns all the response codes whose name or description contains the
+// given keyword, ignoring case.
+func Search(keyword string) []Code {
+	keyword = strings.ToLower(keyword)
+	result := make([]Code, 0)
+	for _, code := range Codes() {
+		if strings.Contains(strings.ToLower(code.Name), keyword) ||
+			strings.Contains(strings.ToLower(code.Description), keyword) {
+			result = append(result, code)
+		}
+	}
+	return result
+}
+
+// MakeFmt1 builds the full value of a FMT1 response code that refers to the
+// idx'th handle, parameter or session of a command.
+func MakeFmt1(c Code, r Relation, idx int) (int, error) {
+	if c.Format != Fmt1 {
+		return 0, fmt.Errorf("%s is a %v code, not a FMT1 code", c.Name, c.Format)
+	}
+	switch r {
+	case Parameter:
+		if idx < 1 || idx > 0xf {
+			return 0, fmt.Errorf("parameter number must be between 1 and 15, got %d", idx)
+		}
+		return c.Value() | 0x40 | (idx << 8), nil
+	case Handle:
+		if idx < 1 || idx > 0x7 {
+			return 0, fmt.Errorf("handle number must be between 1 and 7, got %d", idx)
+		}
+		return c.Value() | (idx << 8), nil
+	case Session:
+		if idx < 1 || idx > 0x7 {
+			return 0, fmt.Errorf("session number must be between 1 and 7, got %d", idx)
+		}
+		return c.Value() | 0x800 | (idx << 8), nil
+	}
+	return 0, fmt.Errorf("unrecognized relation %d", r)
+}
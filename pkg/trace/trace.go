@@ -0,0 +1,112 @@
+// Package trace logs the commands sent to a TPM and the responses it returns,
+// decoded with the command code and response code tables.
+package trace
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chrisfenner/tpm-top/pkg/cc"
+	"github.com/chrisfenner/tpm-top/pkg/rc"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// headerSize is the size of a TPM command or response header: tag, size and
+// command or response code.
+const headerSize = 10
+
+// tracer wraps a TPM connection, logging each command and response.
+type tracer struct {
+	// tpm is the wrapped TPM connection.
+	tpm io.ReadWriteCloser
+	// log is where the trace is written.
+	log io.Writer
+	// cmd is the last command sent to the TPM, if it was recognized.
+	cmd *cc.Command
+	// code is the command code of the last command sent to the TPM.
+	code tpmutil.Command
+	// resp holds the response as it is read, until the header is complete.
+	resp []byte
+}
+
+// New wraps the TPM connection, so that every command and response is logged
+// to log.
+func New(tpm io.ReadWriteCloser, log io.Writer) io.ReadWriteCloser {
+	return &tracer{
+		tpm: tpm,
+		log: log,
+	}
+}
+
+// Write logs the command and sends it to the TPM.
+func (t *tracer) Write(p []byte) (int, error) {
+	t.logCommand(p)
+	t.resp = make([]byte, 0, headerSize)
+	return t.tpm.Write(p)
+}
+
+// Read reads the response from the TPM and logs it.
+func (t *tracer) Read(p []byte) (int, error) {
+	n, err := t.tpm.Read(p)
+	if t.resp != nil {
+		t.resp = append(t.resp, p[:n]...)
+		if len(t.resp) >= headerSize {
+			t.logResponse(t.resp)
+			t.resp = nil
+		}
+	}
+	return n, err
+}
+
+// Close closes the TPM connection.
+func (t *tracer) Close() error {
+	return t.tpm.Close()
+}
+
+// handles decodes up to count handles from the start of the buffer.
+func handles(buf []byte, count int) string {
+	result := make([]string, 0, count)
+	for i := 0; i < count && len(buf) >= 4*(i+1); i++ {
+		result = append(result, fmt.Sprintf("0x%08x", binary.BigEndian.Uint32(buf[4*i:])))
+	}
+	return strings.Join(result, ", ")
+}
+
+func (t *tracer) logCommand(p []byte) {
+	if len(p) < headerSize {
+		fmt.Fprintf(t.log, "> malformed command (%d bytes)\n", len(p))
+		t.cmd = nil
+		return
+	}
+	t.code = tpmutil.Command(binary.BigEndian.Uint32(p[6:]))
+	t.cmd = nil
+	if cmd, ok := cc.ByCode(t.code); ok {
+		t.cmd = &cmd
+	}
+	line := fmt.Sprintf("> %s (0x%x): %d bytes", cc.Name(t.code), uint32(t.code), len(p))
+	if t.cmd != nil && len(t.cmd.Handles) != 0 {
+		names := t.cmd.HandleNames()
+		values := strings.Split(handles(p[headerSize:], len(names)), ", ")
+		for i := range values {
+			values[i] = names[i] + "=" + values[i]
+		}
+		line += ", handles " + strings.Join(values, ", ")
+	}
+	fmt.Fprintf(t.log, "%s\n", line)
+}
+
+func (t *tracer) logResponse(p []byte) {
+	code := int(binary.BigEndian.Uint32(p[6:]))
+	size := binary.BigEndian.Uint32(p[2:])
+	if code != 0 {
+		fmt.Fprintf(t.log, "< %d bytes, %v\n", size, rc.MakeCommandError(code, t.code))
+		return
+	}
+	line := fmt.Sprintf("< %d bytes, TPM_RC_SUCCESS", size)
+	if t.cmd != nil && t.cmd.ResponseHandle != "" && len(p) >= headerSize+4 {
+		line += fmt.Sprintf(", %s=%s", t.cmd.ResponseHandle, handles(p[headerSize:], 1))
+	}
+	fmt.Fprintf(t.log, "%s\n", line)
+}